@@ -0,0 +1,49 @@
+// rebuild-rollups 重建番茄钟的每日汇总
+// 用于首次部署汇总表时补全历史数据，或汇总数据与原始记录不一致时修复
+//
+// 用法：
+//
+//	go run ./cmd/rebuild-rollups            # 重建所有用户
+//	go run ./cmd/rebuild-rollups -user 42   # 只重建指定用户
+//
+// 数据库连接与服务相同，通过DB_DRIVER和DB_DSN环境变量配置
+package main
+
+import (
+	"flag"
+	"log"
+	_ "time/tzdata" // 内嵌时区数据库，与服务保持一致
+
+	"gorm.io/gorm"
+
+	"TomatoList/database"
+	"TomatoList/models"
+)
+
+func main() {
+	userID := flag.Uint("user", 0, "只重建指定用户的汇总，0表示所有用户")
+	flag.Parse()
+
+	database.InitDatabase()
+	db := database.GetDB()
+
+	var userIDs []uint
+	if *userID != 0 {
+		userIDs = []uint{*userID}
+	} else if err := db.Model(&models.User{}).Order("id").Pluck("id", &userIDs).Error; err != nil {
+		log.Fatal("Failed to list users:", err)
+	}
+
+	// 每个用户在单独的事务中重建，失败时不影响已完成的用户
+	for _, id := range userIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return models.RebuildUserRollups(tx, id)
+		})
+		if err != nil {
+			log.Fatalf("Failed to rebuild rollups for user %d: %v", id, err)
+		}
+		log.Printf("Rebuilt rollups for user %d", id)
+	}
+
+	log.Printf("Rebuilt rollups for %d users", len(userIDs))
+}
@@ -42,6 +42,7 @@ func Register(c *gin.Context) {
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6"`
 		Name     string `json:"name" binding:"required"`
+		Timezone string `json:"timezone"` // 可选，IANA时区名称
 	}
 
 	// 绑定JSON数据到结构体
@@ -50,6 +51,13 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// 验证时区
+	loc, err := models.LoadTimezone(request.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 检查邮箱是否已存在
 	var existingUser models.User
 	if result := db.Where("email = ?", request.Email).First(&existingUser); result.Error == nil {
@@ -69,6 +77,7 @@ func Register(c *gin.Context) {
 		Email:    request.Email,
 		Password: string(hashedPassword),
 		Name:     request.Name,
+		Timezone: loc.String(),
 	}
 
 	if result := db.Create(&user); result.Error != nil {
@@ -87,9 +96,10 @@ func Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "注册成功",
 		"user": gin.H{
-			"id":    user.ID,
-			"email": user.Email,
-			"name":  user.Name,
+			"id":       user.ID,
+			"email":    user.Email,
+			"name":     user.Name,
+			"timezone": user.Timezone,
 		},
 		"token": token,
 	})
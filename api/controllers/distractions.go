@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// CreateDistraction 在进行中的番茄钟上记录一次干扰
+// 与Python FastAPI对比：
+// @app.post("/pomodoros/{pomodoro_id}/distractions")
+// async def create_distraction(
+//
+//	pomodoro_id: int,
+//	distraction: DistractionCreate,
+//	db: Session = Depends(get_db),
+//	current_user: User = Depends(get_current_user)
+//
+// ):
+//
+//	pomodoro = db.query(Pomodoro).filter(Pomodoro.id == pomodoro_id, Pomodoro.user_id == current_user.id).first()
+//	if not pomodoro:
+//	    raise HTTPException(status_code=404, detail="番茄钟记录不存在")
+//	if pomodoro.status not in ("进行中", "已暂停"):
+//	    raise HTTPException(status_code=409, detail="只能在进行中的番茄钟上记录干扰")
+//
+//	db_distraction = Distraction(**distraction.dict(), pomodoro_id=pomodoro_id, user_id=current_user.id)
+//	db.add(db_distraction)
+//	db.commit()
+//	return db_distraction
+func CreateDistraction(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	var request struct {
+		Type       string     `json:"type" binding:"required"` // 干扰类型：internal、external
+		Note       string     `json:"note"`                    // 备注
+		OccurredAt *time.Time `json:"occurredAt"`              // 可选，默认为当前时间
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if !models.ValidDistractionType(request.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的干扰类型，必须为internal或external"})
+		return
+	}
+
+	// 查找番茄钟记录
+	var pomodoro models.Pomodoro
+	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		}
+		return
+	}
+
+	if !models.IsActiveStatus(pomodoro.Status) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "只能在进行中的番茄钟上记录干扰",
+			"code":  models.ErrCodeNotActive,
+		})
+		return
+	}
+
+	// 验证发生时间
+	now := time.Now()
+	occurredAt := now
+	if request.OccurredAt != nil {
+		occurredAt = *request.OccurredAt
+		if occurredAt.Before(pomodoro.StartTime) || occurredAt.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "干扰发生时间必须在番茄钟开始之后且不晚于当前时间"})
+			return
+		}
+	}
+
+	distraction := models.Distraction{
+		PomodoroID: pomodoro.ID,
+		UserID:     userID,
+		Type:       request.Type,
+		Note:       request.Note,
+		OccurredAt: occurredAt,
+	}
+
+	if result := db.Create(&distraction); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "记录干扰失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, distraction)
+}
+
+// GetDistractions 获取番茄钟的干扰记录
+func GetDistractions(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	// 确认番茄钟属于当前用户
+	var pomodoro models.Pomodoro
+	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		}
+		return
+	}
+
+	var distractions []models.Distraction
+	if result := db.Where("pomodoro_id = ?", pomodoro.ID).Order("occurred_at").Find(&distractions); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取干扰记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"distractions": distractions,
+		"count":        len(distractions),
+	})
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"TomatoList/models"
+)
+
+// respondPomodoroError 输出番茄钟操作错误：非法状态转换返回409及错误代码，其他错误返回500
+func respondPomodoroError(c *gin.Context, err error, message string) {
+	var transitionErr *models.TransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": transitionErr.Error(),
+			"code":  transitionErr.Code,
+			"from":  transitionErr.From,
+			"to":    transitionErr.To,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
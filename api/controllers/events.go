@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"TomatoList/events"
+	"TomatoList/models"
+)
+
+// StreamPomodoroEvents 通过Server-Sent Events推送当前用户的番茄钟生命周期事件
+// 同一用户的所有客户端（桌面端、手机端）都会收到相同的事件；
+// 断线重连时浏览器会自动携带Last-Event-ID请求头，服务器补发其后的事件
+func StreamPomodoroEvents(c *gin.Context) {
+	hub := c.MustGet("events").(*events.Hub)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取客户端最后收到的事件ID（请求头优先，其次查询参数）
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("lastEventId")
+	}
+	var lastEventID uint64
+	if lastEventIDStr != "" {
+		id, err := strconv.ParseUint(lastEventIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的事件ID"})
+			return
+		}
+		lastEventID = id
+	}
+
+	ch, backlog, cancel := hub.Subscribe(userID, lastEventID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁用Nginx缓冲
+	c.Status(http.StatusOK)
+
+	// 补发断线期间的事件
+	for _, event := range backlog {
+		if err := writeEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	// 定期发送心跳，防止代理断开空闲连接
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			return writeEvent(w, event) == nil
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// writeEvent 按SSE格式写出事件
+func writeEvent(w io.Writer, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// publishPomodoroEvent 向当前用户的所有客户端发布番茄钟事件
+func publishPomodoroEvent(c *gin.Context, eventType string, pomodoro models.Pomodoro) {
+	hub := c.MustGet("events").(*events.Hub)
+	hub.Publish(pomodoro.UserID, eventType, pomodoro)
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+	"TomatoList/stats"
+)
+
+// GoalDay 某一天的目标完成情况
+type GoalDay struct {
+	Date  string `json:"date"`  // 用户时区的日期
+	Count int    `json:"count"` // 已完成的工作时段数量
+	Goal  int    `json:"goal"`  // 当天生效的目标，0表示未设置
+	Met   bool   `json:"met"`   // 是否达成目标
+}
+
+// goalSummary 计算每日目标的进度和连续达成天数
+// 每一天按当天结束时生效的目标判断，未设置目标的日期不算达成；今天尚未达成时不中断连续天数。
+// window不为nil时同时返回范围内每天的完成情况
+func goalSummary(db *gorm.DB, userID uint, loc *time.Location, window *stats.Range) (gin.H, error) {
+	timeline, err := models.GetGoalTimeline(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := stats.StartOfDay(now, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	// 统计需要覆盖的日期范围：从第一次设置目标开始，以及请求的统计范围
+	from, to := today, tomorrow
+	if len(timeline) > 0 {
+		from = stats.StartOfDay(timeline[0].EffectiveFrom, loc)
+	}
+	if window != nil {
+		if window.From.Before(from) {
+			from = window.From
+		}
+		if window.To.After(to) {
+			to = window.To
+		}
+	}
+
+	totals, err := stats.NewService(db).DailyTotals(userID, loc, stats.NewRange(from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	goalDay := func(day time.Time) GoalDay {
+		date := day.Format("2006-01-02")
+		d := GoalDay{Date: date, Count: int(totals[date].Count), Goal: timeline.GoalAt(day.AddDate(0, 0, 1))}
+		d.Met = d.Goal > 0 && d.Count >= d.Goal
+		return d
+	}
+
+	// 从第一次设置目标的日期开始计算连续达成天数
+	var currentStreak, longestStreak int
+	if len(timeline) > 0 {
+		run := 0
+		for day := stats.StartOfDay(timeline[0].EffectiveFrom, loc); day.Before(tomorrow); day = day.AddDate(0, 0, 1) {
+			if goalDay(day).Met {
+				run++
+			} else if !day.Equal(today) {
+				run = 0
+			}
+			if run > longestStreak {
+				longestStreak = run
+			}
+		}
+		currentStreak = run
+	}
+
+	todayProgress := goalDay(today)
+	remaining := todayProgress.Goal - todayProgress.Count
+	if remaining < 0 {
+		remaining = 0
+	}
+	var progress interface{}
+	if todayProgress.Goal > 0 {
+		progress = math.Round(float64(todayProgress.Count)/float64(todayProgress.Goal)*1000) / 10
+	}
+
+	summary := gin.H{
+		"dailyGoal": timeline.Current(),
+		"today": gin.H{
+			"date":      todayProgress.Date,
+			"count":     todayProgress.Count,
+			"goal":      todayProgress.Goal,
+			"met":       todayProgress.Met,
+			"remaining": remaining,
+			"progress":  progress,
+		},
+		"currentStreak": currentStreak,
+		"longestStreak": longestStreak,
+	}
+
+	if window != nil {
+		days := []GoalDay{}
+		for day := window.From; day.Before(window.To); day = day.AddDate(0, 0, 1) {
+			days = append(days, goalDay(day))
+		}
+		summary["days"] = days
+	}
+
+	return summary, nil
+}
+
+// GetGoal 获取每日目标、今日进度、连续达成天数和目标变更历史
+func GetGoal(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return
+	}
+
+	summary, err := goalSummary(db, userID, loc, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取每日目标失败"})
+		return
+	}
+
+	timeline, err := models.GetGoalTimeline(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取目标变更记录失败"})
+		return
+	}
+	summary["history"] = timeline
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// UpdateGoal 设置每日目标，新目标从现在起生效，之前的日期仍按当时的目标判断
+func UpdateGoal(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var request struct {
+		DailyGoal *int `json:"dailyGoal" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if err := models.ValidateDailyGoal(*request.DailyGoal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timeline, err := models.GetGoalTimeline(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取目标变更记录失败"})
+		return
+	}
+
+	// 目标未变化时不记录新的变更
+	if len(timeline) > 0 && timeline.Current() == *request.DailyGoal {
+		c.JSON(http.StatusOK, timeline[len(timeline)-1])
+		return
+	}
+
+	change := models.GoalChange{
+		UserID:        userID,
+		DailyGoal:     *request.DailyGoal,
+		EffectiveFrom: time.Now(),
+	}
+	if result := db.Create(&change); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存每日目标失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
@@ -2,6 +2,9 @@
 package controllers
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,7 +12,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"TomatoList/events"
 	"TomatoList/models"
+	"TomatoList/stats"
 )
 
 // StartPomodoro 开始一个番茄钟
@@ -18,6 +23,7 @@ import (
 // async def start_pomodoro(
 //
 //	task_id: int,
+//	duration_minutes: Optional[int] = None,
 //	db: Session = Depends(get_db),
 //	current_user: User = Depends(get_current_user)
 //
@@ -28,12 +34,16 @@ import (
 //	if not task:
 //	    raise HTTPException(status_code=404, detail="任务不存在")
 //
+//	# 时长：请求覆盖 > 用户设置 > 默认25分钟
+//	settings = get_timer_settings(db, current_user.id)
+//	minutes = duration_minutes or settings.work_minutes
+//
 //	# 创建番茄钟记录
 //	pomodoro = Pomodoro(
 //	    task_id=task_id,
 //	    user_id=current_user.id,
 //	    start_time=datetime.now(),
-//	    expected_end_time=datetime.now() + timedelta(minutes=25)
+//	    expected_end_time=datetime.now() + timedelta(minutes=minutes)
 //	)
 //
 //	db.add(pomodoro)
@@ -46,7 +56,9 @@ func StartPomodoro(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
 
 	var request struct {
-		TaskID uint `json:"taskId" binding:"required"`
+		TaskID          *uint  `json:"taskId"`          // 工作时段必填，休息时段可选
+		Kind            string `json:"kind"`            // 可选，默认为工作时段
+		DurationMinutes int    `json:"durationMinutes"` // 可选，覆盖用户设置的时长
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -54,36 +66,231 @@ func StartPomodoro(c *gin.Context) {
 		return
 	}
 
+	// 验证时段类型
+	if request.Kind == "" {
+		request.Kind = models.KindWork
+	}
+	if !models.ValidKind(request.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟类型"})
+		return
+	}
+	if request.Kind == models.KindWork && request.TaskID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工作时段必须关联任务"})
+		return
+	}
+
 	// 检查任务是否存在且属于当前用户
-	var task models.Task
-	if result := db.Where("id = ? AND user_id = ?", request.TaskID, userID).First(&task); result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+	if request.TaskID != nil {
+		var task models.Task
+		if result := db.Where("id = ? AND user_id = ?", *request.TaskID, userID).First(&task); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+			}
+			return
 		}
+	}
+
+	// 每个用户同一时间只能有一个进行中或已暂停的番茄钟
+	var active models.Pomodoro
+	result := db.Where("user_id = ? AND status IN ?", userID, models.ActiveStatuses).First(&active)
+	if result.Error == nil {
+		respondActiveExists(c, active.ID)
+		return
+	} else if result.Error != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
 		return
 	}
 
-	// 创建番茄钟记录
-	now := time.Now()
+	// 确定时长：请求中的时长优先，否则使用用户设置
+	settings, err := models.GetTimerSettings(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计时器设置失败"})
+		return
+	}
+	minutes := settings.MinutesFor(request.Kind)
+	if request.DurationMinutes != 0 {
+		if !models.ValidSessionMinutes(request.DurationMinutes) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "番茄钟时长必须在1到180分钟之间"})
+			return
+		}
+		minutes = request.DurationMinutes
+	}
+
+	// 创建番茄钟记录，时间以UTC保存
+	now := time.Now().UTC()
 	pomodoro := models.Pomodoro{
 		TaskID:          request.TaskID,
 		UserID:          userID,
+		Kind:            request.Kind,
 		StartTime:       now,
-		ExpectedEndTime: now.Add(25 * time.Minute), // 标准番茄钟25分钟
-		Status:          "进行中",
+		ExpectedEndTime: now.Add(time.Duration(minutes) * time.Minute),
+		Status:          models.StatusRunning,
 	}
 
+	// 并发请求由数据库部分唯一索引兜底
 	if result := db.Create(&pomodoro); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			respondActiveExists(c, 0)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建番茄钟失败"})
+		}
+		return
+	}
+
+	publishPomodoroEvent(c, events.PomodoroStarted, pomodoro)
+	c.JSON(http.StatusCreated, pomodoro)
+}
+
+// respondActiveExists 输出已存在活动番茄钟的冲突错误
+func respondActiveExists(c *gin.Context, activeID uint) {
+	response := gin.H{
+		"error": "已有进行中的番茄钟，请先完成或中断",
+		"code":  models.ErrCodeActiveExists,
+	}
+	if activeID != 0 {
+		response["activePomodoroId"] = activeID
+	}
+	c.JSON(http.StatusConflict, response)
+}
+
+// GetCurrentPomodoro 获取当前进行中或已暂停的番茄钟及剩余时间
+// 没有活动番茄钟时pomodoro为null
+func GetCurrentPomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	now := time.Now()
+	var pomodoro models.Pomodoro
+	result := db.Preload("Task").Preload("Pauses").
+		Where("user_id = ? AND status IN ?", userID, models.ActiveStatuses).
+		First(&pomodoro)
+	if result.Error == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusOK, gin.H{"pomodoro": nil, "serverTime": now})
+		return
+	} else if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pomodoro":         pomodoro,
+		"remainingSeconds": int(pomodoro.Remaining(now).Seconds()),
+		"elapsedSeconds":   int(pomodoro.NetDuration(now).Seconds()),
+		"serverTime":       now,
+	})
+}
+
+// LogManualPomodoro 事后补录一个已完成的番茄钟
+// 用于忘记开始计时的情况，时间段不能与该用户已有的番茄钟重叠
+func LogManualPomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var request struct {
+		TaskID    *uint     `json:"taskId"`                       // 工作时段必填，休息时段可选
+		Kind      string    `json:"kind"`                         // 可选，默认为工作时段
+		StartTime time.Time `json:"startTime" binding:"required"` // 开始时间
+		EndTime   time.Time `json:"endTime" binding:"required"`   // 结束时间
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	// 验证时段类型
+	if request.Kind == "" {
+		request.Kind = models.KindWork
+	}
+	if !models.ValidKind(request.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟类型"})
+		return
+	}
+	if request.Kind == models.KindWork && request.TaskID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工作时段必须关联任务"})
+		return
+	}
+
+	// 验证时间段
+	duration := request.EndTime.Sub(request.StartTime)
+	if duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束时间必须晚于开始时间"})
+		return
+	}
+	if duration > models.MaxSessionMinutes*time.Minute {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "番茄钟时长不能超过180分钟"})
+		return
+	}
+	if request.EndTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能补录未来的番茄钟"})
+		return
+	}
+
+	// 检查任务是否存在且属于当前用户
+	if request.TaskID != nil {
+		var task models.Task
+		if result := db.Where("id = ? AND user_id = ?", *request.TaskID, userID).First(&task); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+			}
+			return
+		}
+	}
+
+	pomodoro := models.Pomodoro{
+		TaskID:          request.TaskID,
+		UserID:          userID,
+		Kind:            request.Kind,
+		StartTime:       request.StartTime.UTC(),
+		EndTime:         request.EndTime.UTC(),
+		ExpectedEndTime: request.EndTime.UTC(),
+		Status:          models.StatusCompleted,
+		FocusedSeconds:  int(duration.Seconds()),
+		IsManual:        true,
+	}
+
+	// 检查重叠与创建放在同一事务中，先锁定用户行，避免并发补录都通过重叠检查
+	var overlapping *models.Pomodoro
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := models.LockUser(tx, userID); err != nil {
+			return err
+		}
+		var err error
+		overlapping, err = models.FindOverlapping(tx, userID, request.StartTime, request.EndTime, 0)
+		if err != nil || overlapping != nil {
+			return err
+		}
+		if err := tx.Create(&pomodoro).Error; err != nil {
+			return err
+		}
+		return models.RefreshDailyRollups(tx, userID, pomodoro.StartTime)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建番茄钟失败"})
 		return
 	}
+	if overlapping != nil {
+		respondOverlap(c, overlapping)
+		return
+	}
 
 	c.JSON(http.StatusCreated, pomodoro)
 }
 
-// CompletePomodoro 完成一个番茄钟
+// respondOverlap 输出时间重叠的冲突错误
+func respondOverlap(c *gin.Context, overlapping *models.Pomodoro) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":                 "与已有的番茄钟时间重叠",
+		"code":                  models.ErrCodeOverlap,
+		"overlappingPomodoroId": overlapping.ID,
+	})
+}
+
+// CompletePomodoro 完成一个番茄钟，可同时提交复盘信息（完成内容、专注度评分、精力/心情）
 func CompletePomodoro(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	userID := c.MustGet("userID").(uint)
@@ -95,9 +302,83 @@ func CompletePomodoro(c *gin.Context) {
 		return
 	}
 
+	// 可选的复盘信息，请求体可以为空
+	var request struct {
+		Notes       string `json:"notes"`                                       // 完成了什么
+		FocusRating int    `json:"focusRating" binding:"omitempty,min=1,max=5"` // 专注度评分1-5
+		Mood        int    `json:"mood" binding:"omitempty,min=1,max=5"`        // 精力/心情1-5
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+		return
+	}
+
 	// 查找番茄钟记录
 	var pomodoro models.Pomodoro
-	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+	if result := db.Preload("Pauses").Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		}
+		return
+	}
+
+	// 已完成、已中断的番茄钟不能再次完成，避免覆盖结束时间
+	if err := models.CheckTransition(pomodoro.Status, models.StatusCompleted); err != nil {
+		respondPomodoroError(c, err, "更新番茄钟失败")
+		return
+	}
+
+	updates := map[string]interface{}{
+		"notes":        request.Notes,
+		"focus_rating": request.FocusRating,
+		"mood":         request.Mood,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return pomodoro.Finish(tx, models.StatusCompleted, time.Now(), updates)
+	})
+	if err != nil {
+		respondPomodoroError(c, err, "更新番茄钟失败")
+		return
+	}
+
+	publishPomodoroEvent(c, events.PomodoroCompleted, pomodoro)
+	c.JSON(http.StatusOK, pomodoro)
+}
+
+// InterruptPomodoro 中断一个进行中的番茄钟，并记录中断原因
+func InterruptPomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason" binding:"required"` // 中断原因代码
+		Note   string `json:"note"`                      // 备注
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if !models.ValidInterruptReason(request.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的中断原因", "validReasons": models.InterruptReasons})
+		return
+	}
+
+	// 查找番茄钟记录
+	var pomodoro models.Pomodoro
+	if result := db.Preload("Pauses").Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
 		} else {
@@ -106,20 +387,185 @@ func CompletePomodoro(c *gin.Context) {
 		return
 	}
 
+	if err := models.CheckTransition(pomodoro.Status, models.StatusInterrupted); err != nil {
+		respondPomodoroError(c, err, "更新番茄钟失败")
+		return
+	}
+
 	// 更新番茄钟状态
 	updates := map[string]interface{}{
-		"end_time": time.Now(),
-		"status":   "已完成",
+		"interrupt_reason": request.Reason,
+		"interrupt_note":   request.Note,
 	}
 
-	if result := db.Model(&pomodoro).Updates(updates); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新番茄钟失败"})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return pomodoro.Finish(tx, models.StatusInterrupted, time.Now(), updates)
+	})
+	if err != nil {
+		respondPomodoroError(c, err, "更新番茄钟失败")
 		return
 	}
 
+	publishPomodoroEvent(c, events.PomodoroInterrupted, pomodoro)
 	c.JSON(http.StatusOK, pomodoro)
 }
 
+// PausePomodoro 暂停一个进行中的番茄钟
+func PausePomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	// 查找番茄钟记录
+	var pomodoro models.Pomodoro
+	if result := db.Preload("Pauses").Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		}
+		return
+	}
+
+	if err := models.CheckTransition(pomodoro.Status, models.StatusPaused); err != nil {
+		respondPomodoroError(c, err, "暂停番茄钟失败")
+		return
+	}
+
+	// 创建暂停片段并更新状态
+	pause := models.PomodoroPause{
+		PomodoroID: pomodoro.ID,
+		StartTime:  time.Now().UTC(),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&pause).Error; err != nil {
+			return err
+		}
+		return pomodoro.Transition(tx, models.StatusPaused, map[string]interface{}{})
+	})
+	if err != nil {
+		respondPomodoroError(c, err, "暂停番茄钟失败")
+		return
+	}
+
+	pomodoro.Pauses = append(pomodoro.Pauses, pause)
+	publishPomodoroEvent(c, events.PomodoroPaused, pomodoro)
+	c.JSON(http.StatusOK, pomodoro)
+}
+
+// ResumePomodoro 恢复一个已暂停的番茄钟
+// 预期结束时间顺延本次暂停的时长
+func ResumePomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	// 查找番茄钟记录
+	var pomodoro models.Pomodoro
+	if result := db.Preload("Pauses").Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		}
+		return
+	}
+
+	if err := models.CheckTransition(pomodoro.Status, models.StatusRunning); err != nil {
+		respondPomodoroError(c, err, "恢复番茄钟失败")
+		return
+	}
+
+	pause := pomodoro.OpenPause()
+	if pause == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "暂停记录不存在"})
+		return
+	}
+
+	// 结束暂停片段，顺延预期结束时间
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"expected_end_time": pomodoro.ExpectedEndTime.Add(now.Sub(pause.StartTime)).UTC(),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(pause).Update("end_time", now).Error; err != nil {
+			return err
+		}
+		return pomodoro.Transition(tx, models.StatusRunning, updates)
+	})
+	if err != nil {
+		respondPomodoroError(c, err, "恢复番茄钟失败")
+		return
+	}
+
+	publishPomodoroEvent(c, events.PomodoroResumed, pomodoro)
+	c.JSON(http.StatusOK, pomodoro)
+}
+
+// GetNextSession 根据最近的番茄钟记录推算下一个时段的类型和时长
+// 完成一个工作时段后进入休息，每完成LongBreakInterval个工作时段进行一次长休息
+func GetNextSession(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	settings, err := models.GetTimerSettings(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计时器设置失败"})
+		return
+	}
+
+	// 最近一次完成的时段
+	var last models.Pomodoro
+	lastKind := ""
+	result := db.Where("user_id = ? AND status = ?", userID, models.StatusCompleted).Order("start_time desc").First(&last)
+	if result.Error == nil {
+		lastKind = last.Kind
+	} else if result.Error != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟记录失败"})
+		return
+	}
+
+	// 上次长休息之后完成的工作时段数量
+	query := db.Model(&models.Pomodoro{}).Where("user_id = ? AND kind = ? AND status = ?", userID, models.KindWork, models.StatusCompleted)
+	var lastLongBreak models.Pomodoro
+	result = db.Where("user_id = ? AND kind = ? AND status = ?", userID, models.KindLongBreak, models.StatusCompleted).Order("start_time desc").First(&lastLongBreak)
+	if result.Error == nil {
+		query = query.Where("start_time > ?", lastLongBreak.StartTime)
+	} else if result.Error != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟记录失败"})
+		return
+	}
+
+	var workSinceLongBreak int64
+	if result := query.Count(&workSinceLongBreak); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟记录失败"})
+		return
+	}
+
+	kind := models.NextSessionKind(lastKind, int(workSinceLongBreak), settings.LongBreakInterval)
+
+	c.JSON(http.StatusOK, gin.H{
+		"kind":               kind,
+		"durationMinutes":    settings.MinutesFor(kind),
+		"workSinceLongBreak": workSinceLongBreak,
+		"longBreakInterval":  settings.LongBreakInterval,
+	})
+}
+
 // GetPomodoros 获取用户的番茄钟记录
 func GetPomodoros(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
@@ -177,67 +623,428 @@ func GetPomodoros(c *gin.Context) {
 }
 
 // GetPomodoroStats 获取番茄钟统计信息
+// 时间范围使用from/to（用户时区的日期，包含两端）或days（默认最近7天，含今天）；
+// series按granularity（hour、day、week、month、year）分组并补齐没有数据的时间段，
+// comparison为与上一个等长周期的对比，groupBy=task或project时返回按任务或项目分组的统计
 func GetPomodoroStats(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	userID := c.MustGet("userID").(uint)
 
-	// 获取时间范围参数（默认最近7天）
-	daysStr := c.DefaultQuery("days", "7")
-	days, err := strconv.Atoi(daysStr)
-	if err != nil || days < 1 {
-		days = 7
+	// 按用户时区的日历日期计算时间范围和分组
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return
+	}
+	window, ok := parseStatsRange(c, loc, 7)
+	if !ok {
+		return
+	}
+	buckets, ok := parseBucketer(c, loc)
+	if !ok {
+		return
 	}
 
-	startDate := time.Now().AddDate(0, 0, -days)
+	// 可选的分组统计
+	groupBy := c.Query("groupBy")
+	if groupBy != "" && groupBy != stats.GroupByTask && groupBy != stats.GroupByProject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组方式，可选值：task、project"})
+		return
+	}
 
-	// 查询已完成番茄钟数量
-	var completedCount int64
-	db.Model(&models.Pomodoro{}).
-		Where("user_id = ? AND status = ? AND start_time >= ?", userID, "已完成", startDate).
-		Count(&completedCount)
+	// 是否包含手动补录的番茄钟（默认包含）
+	includeManual := true
+	if includeManualStr := c.Query("includeManual"); includeManualStr != "" {
+		if v, err := strconv.ParseBool(includeManualStr); err == nil {
+			includeManual = v
+		}
+	}
 
-	// 查询总番茄钟时间（分钟）
-	var totalMinutes int64
-	rows, err := db.Model(&models.Pomodoro{}).
-		Select("COALESCE(SUM(EXTRACT(EPOCH FROM (end_time - start_time)) / 60), 0)").
-		Where("user_id = ? AND status = ? AND start_time >= ?", userID, "已完成", startDate).
-		Rows()
+	summary, err := stats.NewService(db).Summary(stats.Query{
+		UserID:        userID,
+		Range:         window,
+		Bucketer:      buckets,
+		IncludeManual: includeManual,
+		GroupBy:       groupBy,
+	})
+	if errors.Is(err, stats.ErrTooManyBuckets) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟统计失败"})
+		return
+	}
+
+	// 每日目标进度和连续达成天数
+	goal, err := goalSummary(db, userID, loc, &window)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取每日目标失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		*stats.Summary
+		Goal gin.H `json:"goal"`
+	}{summary, goal})
+}
+
+// UpdatePomodoro 修正一个番茄钟记录
+// 可修改关联任务、开始/结束时间和状态；时间不能与其他番茄钟重叠，状态只能在终止状态之间修正
+// 每次修改都会保存修改前后的快照
+func UpdatePomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	var request struct {
+		TaskID          *uint      `json:"taskId"`
+		StartTime       *time.Time `json:"startTime"`
+		EndTime         *time.Time `json:"endTime"`
+		Status          *string    `json:"status"`
+		InterruptReason *string    `json:"interruptReason"`
+		InterruptNote   *string    `json:"interruptNote"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
 
-	if err == nil {
-		defer rows.Close()
-		if rows.Next() {
-			rows.Scan(&totalMinutes)
+	// 查找番茄钟记录
+	var pomodoro models.Pomodoro
+	if result := db.Preload("Pauses").Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
 		}
+		return
+	}
+
+	// 进行中的番茄钟只能修改关联任务，状态和时间需通过对应的操作接口变更
+	if models.IsActiveStatus(pomodoro.Status) &&
+		(request.StartTime != nil || request.EndTime != nil || request.Status != nil ||
+			request.InterruptReason != nil || request.InterruptNote != nil) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "进行中的番茄钟只能修改关联任务",
+			"code":  models.ErrCodeStillActive,
+		})
+		return
 	}
 
-	// 查询每日番茄钟数量
-	type DailyStat struct {
-		Date  string `json:"date"`
-		Count int    `json:"count"`
+	before := pomodoro.Snapshot()
+	updates := map[string]interface{}{}
+
+	// 修改关联任务
+	if request.TaskID != nil {
+		var task models.Task
+		if result := db.Where("id = ? AND user_id = ?", *request.TaskID, userID).First(&task); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+			}
+			return
+		}
+		updates["task_id"] = *request.TaskID
 	}
-	var dailyStats []DailyStat
 
-	rows, err = db.Model(&models.Pomodoro{}).
-		Select("DATE(start_time) as date, COUNT(*) as count").
-		Where("user_id = ? AND status = ? AND start_time >= ?", userID, "已完成", startDate).
-		Group("DATE(start_time)").
-		Order("date").
-		Rows()
+	// 修正状态
+	status := pomodoro.Status
+	if request.Status != nil {
+		if err := models.CheckCorrection(pomodoro.Status, *request.Status); err != nil {
+			respondPomodoroError(c, err, "更新番茄钟失败")
+			return
+		}
+		status = *request.Status
+		updates["status"] = status
+	}
+	if status == models.StatusInterrupted {
+		reason := pomodoro.InterruptReason
+		if request.InterruptReason != nil {
+			reason = *request.InterruptReason
+		}
+		if reason == "" {
+			reason = models.InterruptOther
+		}
+		if !models.ValidInterruptReason(reason) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的中断原因", "validReasons": models.InterruptReasons})
+			return
+		}
+		updates["interrupt_reason"] = reason
+		if request.InterruptNote != nil {
+			updates["interrupt_note"] = *request.InterruptNote
+		}
+	} else if pomodoro.InterruptReason != "" || pomodoro.InterruptNote != "" {
+		// 不再是中断状态时清除中断原因
+		updates["interrupt_reason"] = ""
+		updates["interrupt_note"] = ""
+	}
+
+	// 修正时间
+	timesChanged := request.StartTime != nil || request.EndTime != nil
+	if timesChanged {
+		if request.StartTime != nil {
+			pomodoro.StartTime = *request.StartTime
+		}
+		if request.EndTime != nil {
+			pomodoro.EndTime = *request.EndTime
+		}
+
+		duration := pomodoro.EndTime.Sub(pomodoro.StartTime)
+		if duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "结束时间必须晚于开始时间"})
+			return
+		}
+		if duration > models.MaxSessionMinutes*time.Minute {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "番茄钟时长不能超过180分钟"})
+			return
+		}
+		if pomodoro.EndTime.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "结束时间不能晚于当前时间"})
+			return
+		}
+
+		updates["start_time"] = pomodoro.StartTime.UTC()
+		updates["end_time"] = pomodoro.EndTime.UTC()
+	}
+	if timesChanged || request.Status != nil {
+		updates["focused_seconds"] = int(pomodoro.NetDuration(pomodoro.EndTime).Seconds())
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+
+	// 检查重叠、更新记录和保存修改历史放在同一事务中，先锁定用户行，避免并发修改都通过重叠检查
+	// 已过期的番茄钟不参与重叠检查，修正为其他状态时即使时间不变也要检查
+	checkOverlap := timesChanged || (before.Status == models.StatusExpired && status != models.StatusExpired)
+	var overlapping *models.Pomodoro
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := models.LockUser(tx, userID); err != nil {
+			return err
+		}
+		if checkOverlap {
+			var err error
+			overlapping, err = models.FindOverlapping(tx, userID, pomodoro.StartTime, pomodoro.EndTime, pomodoro.ID)
+			if err != nil || overlapping != nil {
+				return err
+			}
+		}
+
+		// 更新条件包含原状态，防止与清理任务等并发修改冲突
+		result := tx.Model(&pomodoro).Where("status = ?", before.Status).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &models.TransitionError{Code: models.ErrCodeStaleStatus, From: before.Status, To: status}
+		}
+
+		revision := models.PomodoroRevision{
+			PomodoroID: pomodoro.ID,
+			UserID:     userID,
+			Action:     models.RevisionUpdate,
+			Before:     before,
+			After:      pomodoro.Snapshot(),
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		// 开始时间可能跨日期修改，修改前后的日期都需要重新汇总
+		return models.RefreshDailyRollups(tx, userID, before.StartTime, pomodoro.StartTime)
+	})
+	if err != nil {
+		respondPomodoroError(c, err, "更新番茄钟失败")
+		return
+	}
+	if overlapping != nil {
+		respondOverlap(c, overlapping)
+		return
+	}
+
+	publishPomodoroEvent(c, events.PomodoroUpdated, pomodoro)
+	c.JSON(http.StatusOK, pomodoro)
+}
+
+// DeletePomodoro 删除一个番茄钟记录，删除前的数据保存在修改历史中
+func DeletePomodoro(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	// 查找番茄钟记录
+	var pomodoro models.Pomodoro
+	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&pomodoro); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "番茄钟记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟失败"})
+		}
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		revision := models.PomodoroRevision{
+			PomodoroID: pomodoro.ID,
+			UserID:     userID,
+			Action:     models.RevisionDelete,
+			Before:     pomodoro.Snapshot(),
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&pomodoro).Error; err != nil {
+			return err
+		}
+		return models.RefreshDailyRollups(tx, userID, pomodoro.StartTime)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除番茄钟失败"})
+		return
+	}
+
+	publishPomodoroEvent(c, events.PomodoroDeleted, pomodoro)
+	c.JSON(http.StatusOK, gin.H{"message": "番茄钟删除成功"})
+}
+
+// GetPomodoroHistory 获取番茄钟记录的修改历史（记录删除后仍可查询）
+func GetPomodoroHistory(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取番茄钟ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的番茄钟ID"})
+		return
+	}
+
+	var revisions []models.PomodoroRevision
+	if result := db.Where("pomodoro_id = ? AND user_id = ?", id, userID).Order("created_at").Find(&revisions); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取修改历史失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// GetFocusStats 获取专注度复盘统计
+// 按开始时间所在的小时和任务优先级分组，计算已评分番茄钟的平均专注度和精力
+func GetFocusStats(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 按用户时区计算时间范围（默认最近30天）和小时分组
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return
+	}
+	window, ok := parseStatsRange(c, loc, 30)
+	if !ok {
+		return
+	}
+
+	// 查询已评分的工作时段
+	var rated []struct {
+		StartTime   time.Time
+		FocusRating int
+		Mood        int
+		Priority    *string
+	}
+	result := db.Model(&models.Pomodoro{}).
+		Select("pomodoros.start_time, pomodoros.focus_rating, pomodoros.mood, tasks.priority").
+		Joins("LEFT JOIN tasks ON tasks.id = pomodoros.task_id").
+		Where("pomodoros.user_id = ? AND pomodoros.kind = ? AND pomodoros.status = ? AND pomodoros.focus_rating > 0",
+			userID, models.KindWork, models.StatusCompleted).
+		Where("pomodoros.start_time >= ? AND pomodoros.start_time < ?", window.From.UTC(), window.To.UTC()).
+		Scan(&rated)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取专注度统计失败"})
+		return
+	}
+
+	// 分组累计
+	type focusGroup struct {
+		sessions    int
+		ratingSum   int
+		moodSum     int
+		moodEntries int
+	}
+	add := func(g *focusGroup, focusRating, mood int) {
+		g.sessions++
+		g.ratingSum += focusRating
+		if mood > 0 {
+			g.moodSum += mood
+			g.moodEntries++
+		}
+	}
+	summarize := func(g *focusGroup) gin.H {
+		summary := gin.H{"sessions": g.sessions, "averageFocus": nil, "averageMood": nil}
+		if g.sessions > 0 {
+			summary["averageFocus"] = math.Round(float64(g.ratingSum)/float64(g.sessions)*100) / 100
+		}
+		if g.moodEntries > 0 {
+			summary["averageMood"] = math.Round(float64(g.moodSum)/float64(g.moodEntries)*100) / 100
+		}
+		return summary
+	}
+
+	var overall focusGroup
+	var byHour [24]focusGroup
+	byPriority := map[string]*focusGroup{}
+	for _, r := range rated {
+		add(&overall, r.FocusRating, r.Mood)
+		add(&byHour[r.StartTime.In(loc).Hour()], r.FocusRating, r.Mood)
+
+		priority := "无任务"
+		if r.Priority != nil {
+			priority = *r.Priority
+		}
+		if byPriority[priority] == nil {
+			byPriority[priority] = &focusGroup{}
+		}
+		add(byPriority[priority], r.FocusRating, r.Mood)
+	}
+
+	hourStats := []gin.H{}
+	for hour := range byHour {
+		if byHour[hour].sessions == 0 {
+			continue
+		}
+		stat := summarize(&byHour[hour])
+		stat["hour"] = hour
+		hourStats = append(hourStats, stat)
+	}
 
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var date string
-			var count int
-			rows.Scan(&date, &count)
-			dailyStats = append(dailyStats, DailyStat{Date: date, Count: count})
+	priorityStats := []gin.H{}
+	for _, priority := range []string{"高", "中", "低", "无任务"} {
+		if g := byPriority[priority]; g != nil {
+			stat := summarize(g)
+			stat["priority"] = priority
+			priorityStats = append(priorityStats, stat)
 		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"completedCount": completedCount,
-		"totalMinutes":   totalMinutes,
-		"dailyStats":     dailyStats,
-		"period":         days,
+		"overall":    summarize(&overall),
+		"byHour":     hourStats,
+		"byPriority": priorityStats,
+		"period":     window.Days,
+		"timezone":   loc.String(),
 	})
 }
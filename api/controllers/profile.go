@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// GetProfile 获取当前用户的资料
+func GetProfile(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var user models.User
+	if result := db.First(&user, userID); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile 更新当前用户的资料（用户名、时区）
+// 未提供的字段保持原值
+func UpdateProfile(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var request struct {
+		Name     *string `json:"name"`
+		Timezone *string `json:"timezone"` // IANA时区名称，如Asia/Shanghai
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	var user models.User
+	if result := db.First(&user, userID); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		}
+		return
+	}
+
+	updates := map[string]interface{}{}
+	timezoneChanged := false
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能为空"})
+			return
+		}
+		updates["name"] = name
+	}
+	if request.Timezone != nil {
+		loc, err := models.LoadTimezone(*request.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["timezone"] = loc.String()
+		timezoneChanged = loc.String() != user.Timezone
+	}
+
+	if len(updates) > 0 {
+		// 每日汇总按用户时区的日期分组，修改时区时在同一事务中重建
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			if timezoneChanged {
+				return models.RebuildUserRollups(tx, userID)
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户资料失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, user)
+}
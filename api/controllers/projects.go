@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// GetProjects 获取用户的项目列表，按排列顺序返回，附带每个项目的任务数量
+// archived参数可只返回已归档（true）或未归档（false）的项目
+func GetProjects(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	query := db.Where("user_id = ?", userID)
+
+	// 过滤归档状态
+	if archivedStr := c.Query("archived"); archivedStr != "" {
+		archived, err := strconv.ParseBool(archivedStr)
+		if err == nil {
+			query = query.Where("archived = ?", archived)
+		}
+	}
+
+	projects := []models.Project{}
+	if result := query.Order("position, id").Find(&projects); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目失败"})
+		return
+	}
+
+	if err := models.FillTaskCounts(db, projects); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目任务数量失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"projects": projects})
+}
+
+// CreateProject 创建新项目，未指定位置时排在最后
+func CreateProject(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var request struct {
+		Name     string `json:"name" binding:"required"`
+		Color    string `json:"color"`
+		Position *int   `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	name, err := models.NormalizeProjectName(request.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project := models.Project{UserID: userID, Name: name, Color: models.DefaultProjectColor}
+	if request.Color != "" {
+		if err := models.ValidateProjectColor(request.Color); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		project.Color = request.Color
+	}
+
+	if request.Position != nil {
+		project.Position = *request.Position
+	} else {
+		position, err := models.NextProjectPosition(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建项目失败"})
+			return
+		}
+		project.Position = position
+	}
+
+	if result := db.Create(&project); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建项目失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProject 更新项目的名称、颜色、归档状态或排列位置，未提供的字段保持原值
+func UpdateProject(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取项目ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+		return
+	}
+
+	project, ok := findProject(c, db, userID, uint(id))
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Name     *string `json:"name"`
+		Color    *string `json:"color"`
+		Archived *bool   `json:"archived"`
+		Position *int    `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if request.Name != nil {
+		name, err := models.NormalizeProjectName(*request.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["name"] = name
+	}
+	if request.Color != nil {
+		if err := models.ValidateProjectColor(*request.Color); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["color"] = *request.Color
+	}
+	if request.Archived != nil {
+		updates["archived"] = *request.Archived
+	}
+	if request.Position != nil {
+		updates["position"] = *request.Position
+	}
+
+	if len(updates) > 0 {
+		if result := db.Model(&project).Updates(updates); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新项目失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject 删除项目，项目中的任务保留并变为未分组
+func DeleteProject(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取项目ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+		return
+	}
+
+	project, ok := findProject(c, db, userID, uint(id))
+	if !ok {
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("project_id = ?", project.ID).Update("project_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&project).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "项目删除成功"})
+}
+
+// findProject 查找属于当前用户的项目
+// 出错时已写入响应，返回false
+func findProject(c *gin.Context, db *gorm.DB, userID, id uint) (models.Project, bool) {
+	var project models.Project
+	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&project); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目失败"})
+		}
+		return project, false
+	}
+	return project, true
+}
+
+// checkTaskProject 检查任务要加入的项目属于当前用户且未归档
+// 出错时已写入响应，返回false
+func checkTaskProject(c *gin.Context, db *gorm.DB, userID, projectID uint) bool {
+	project, ok := findProject(c, db, userID, projectID)
+	if !ok {
+		return false
+	}
+	if project.Archived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目已归档，不能添加任务"})
+		return false
+	}
+	return true
+}
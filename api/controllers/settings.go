@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// GetTimerSettings 获取当前用户的计时器设置
+// 与Python FastAPI对比：
+// @app.get("/settings/timer")
+// async def get_timer_settings(
+//
+//	db: Session = Depends(get_db),
+//	current_user: User = Depends(get_current_user)
+//
+// ):
+//
+//	settings = db.query(TimerSettings).filter(TimerSettings.user_id == current_user.id).first()
+//	return settings or TimerSettings.defaults(current_user.id)
+func GetTimerSettings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	settings, err := models.GetTimerSettings(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计时器设置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateTimerSettings 更新当前用户的计时器设置
+// 未提供的字段保持原值（首次设置时使用默认值）
+func UpdateTimerSettings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var request struct {
+		WorkMinutes       *int    `json:"workMinutes"`
+		ShortBreakMinutes *int    `json:"shortBreakMinutes"`
+		LongBreakMinutes  *int    `json:"longBreakMinutes"`
+		LongBreakInterval *int    `json:"longBreakInterval"`
+		OverduePolicy     *string `json:"overduePolicy"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	settings, err := models.GetTimerSettings(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计时器设置失败"})
+		return
+	}
+
+	// 合并更新字段
+	if request.WorkMinutes != nil {
+		settings.WorkMinutes = *request.WorkMinutes
+	}
+	if request.ShortBreakMinutes != nil {
+		settings.ShortBreakMinutes = *request.ShortBreakMinutes
+	}
+	if request.LongBreakMinutes != nil {
+		settings.LongBreakMinutes = *request.LongBreakMinutes
+	}
+	if request.LongBreakInterval != nil {
+		settings.LongBreakInterval = *request.LongBreakInterval
+	}
+	if request.OverduePolicy != nil {
+		settings.OverduePolicy = *request.OverduePolicy
+	}
+
+	// 验证数据
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 首次保存时创建记录，否则更新
+	if result := db.Save(&settings); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存计时器设置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// GetShareTokens 获取用户的分享令牌列表（不包含令牌明文）
+func GetShareTokens(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	tokens := []models.ShareToken{}
+	if result := db.Where("user_id = ?", userID).Order("id").Find(&tokens); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shareTokens": tokens})
+}
+
+// CreateShareToken 创建只读分享令牌，令牌明文只在本次响应中返回
+// 例如scope为calendar的令牌可用于 /api/pomodoros/stats/calendar.svg?token=...
+func CreateShareToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var request struct {
+		Scope string `json:"scope"`
+		Name  string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if request.Scope == "" {
+		request.Scope = models.ShareScopeCalendar
+	}
+	if !models.ValidShareScope(request.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分享令牌用途"})
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if len([]rune(name)) > models.MaxShareTokenNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "分享令牌名称不能超过50个字符"})
+		return
+	}
+
+	raw, token, err := models.NewShareToken(userID, request.Scope, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建分享令牌失败"})
+		return
+	}
+	if result := db.Create(&token); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建分享令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"shareToken": token, "token": raw})
+}
+
+// DeleteShareToken 撤销分享令牌，使用该令牌的链接立即失效
+func DeleteShareToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取令牌ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分享令牌ID"})
+		return
+	}
+
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ShareToken{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "撤销分享令牌失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享令牌不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "分享令牌已撤销"})
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/stats"
+)
+
+// focusCalendar 计算当前用户最近365天的专注日历，出错时已写入响应，返回nil
+func focusCalendar(c *gin.Context) *stats.Calendar {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return nil
+	}
+	weekStart, ok := parseWeekStart(c)
+	if !ok {
+		return nil
+	}
+
+	calendar, err := stats.NewService(db).Calendar(userID, loc, weekStart, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取专注日历失败"})
+		return nil
+	}
+	return calendar
+}
+
+// GetFocusCalendar 获取最近365天的专注日历
+// 每天返回完成数量、专注时长和0-4的强度等级（按有记录日期的专注时长四分位数划分）
+func GetFocusCalendar(c *gin.Context) {
+	if calendar := focusCalendar(c); calendar != nil {
+		c.JSON(http.StatusOK, calendar)
+	}
+}
+
+// GetFocusCalendarSVG 以SVG图片返回最近365天的专注日历
+// 通过token查询参数传递只读分享令牌认证，便于以<img>嵌入到团队wiki而不暴露登录令牌
+func GetFocusCalendarSVG(c *gin.Context) {
+	if calendar := focusCalendar(c); calendar != nil {
+		c.Header("Cache-Control", "private, max-age=300")
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", calendar.RenderSVG())
+	}
+}
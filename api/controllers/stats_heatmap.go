@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// HeatmapCell 热力图中某个星期几、某个小时的统计
+type HeatmapCell struct {
+	Count   int     `json:"count"`   // 已完成的工作时段数量
+	Minutes float64 `json:"minutes"` // 净专注时长（分钟）
+}
+
+// FocusWindow 推荐的专注时间窗口
+type FocusWindow struct {
+	Weekday   string  `json:"weekday"`   // 星期几（小写英文，如monday）
+	StartHour int     `json:"startHour"` // 开始小时（包含）
+	EndHour   int     `json:"endHour"`   // 结束小时（不包含）
+	Count     int     `json:"count"`
+	Minutes   float64 `json:"minutes"`
+}
+
+// GetFocusHeatmap 获取星期几×小时的专注热力图
+// 按用户时区中的开始时间，将已完成的工作时段计入7×24的矩阵；行从weekStart（默认周一）开始。
+// 同时返回专注时长最多的若干个互不重叠的时间窗口（windowHours小时，默认2；top个，默认3）
+func GetFocusHeatmap(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 按用户时区计算时间范围（默认最近30天）
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return
+	}
+	window, ok := parseStatsRange(c, loc, 30)
+	if !ok {
+		return
+	}
+
+	weekStart, ok := parseWeekStart(c)
+	if !ok {
+		return
+	}
+
+	windowHours, err := strconv.Atoi(c.DefaultQuery("windowHours", "2"))
+	if err != nil || windowHours < 1 || windowHours > 12 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "时间窗口长度必须在1到12小时之间"})
+		return
+	}
+	top, err := strconv.Atoi(c.DefaultQuery("top", "3"))
+	if err != nil || top < 1 || top > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "推荐数量必须在1到10之间"})
+		return
+	}
+
+	var sessions []struct {
+		StartTime      time.Time
+		FocusedSeconds int
+	}
+	result := db.Model(&models.Pomodoro{}).
+		Select("start_time, focused_seconds").
+		Where("user_id = ? AND kind = ? AND status = ?", userID, models.KindWork, models.StatusCompleted).
+		Where("start_time >= ? AND start_time < ?", window.From.UTC(), window.To.UTC()).
+		Scan(&sessions)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取专注热力图失败"})
+		return
+	}
+
+	// matrix[行][小时]，第0行为weekStart
+	var matrix [7][24]HeatmapCell
+	for _, session := range sessions {
+		start := session.StartTime.In(loc)
+		row := (int(start.Weekday()) - int(weekStart) + 7) % 7
+		cell := &matrix[row][start.Hour()]
+		cell.Count++
+		cell.Minutes += float64(session.FocusedSeconds) / 60
+	}
+
+	weekdays := make([]string, 7)
+	for row := range matrix {
+		weekdays[row] = strings.ToLower(time.Weekday((int(weekStart) + row) % 7).String())
+		for hour := range matrix[row] {
+			matrix[row][hour].Minutes = math.Round(matrix[row][hour].Minutes*10) / 10
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"weekdays": weekdays,
+		"matrix":   matrix,
+		"windows":  topFocusWindows(matrix, weekdays, windowHours, top),
+		"from":     window.FromDate(),
+		"to":       window.ToDate(),
+		"timezone": loc.String(),
+	})
+}
+
+// topFocusWindows 找出专注时长最多的top个互不重叠的时间窗口，窗口不跨越午夜
+func topFocusWindows(matrix [7][24]HeatmapCell, weekdays []string, hours, top int) []FocusWindow {
+	type candidate struct {
+		row, start int
+		FocusWindow
+	}
+
+	var candidates []candidate
+	for row := range matrix {
+		for start := 0; start+hours <= 24; start++ {
+			w := candidate{row: row, start: start, FocusWindow: FocusWindow{
+				Weekday:   weekdays[row],
+				StartHour: start,
+				EndHour:   start + hours,
+			}}
+			for hour := start; hour < start+hours; hour++ {
+				w.Count += matrix[row][hour].Count
+				w.Minutes += matrix[row][hour].Minutes
+			}
+			if w.Count > 0 {
+				w.Minutes = math.Round(w.Minutes*10) / 10
+				candidates = append(candidates, w)
+			}
+		}
+	}
+
+	// 按专注时长、数量降序，相同时取更早的窗口
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Minutes != candidates[j].Minutes {
+			return candidates[i].Minutes > candidates[j].Minutes
+		}
+		return candidates[i].Count > candidates[j].Count
+	})
+
+	windows := []FocusWindow{}
+	var used [7][24]bool
+	for _, w := range candidates {
+		if len(windows) == top {
+			break
+		}
+		overlaps := false
+		for hour := w.start; hour < w.start+hours; hour++ {
+			if used[w.row][hour] {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		for hour := w.start; hour < w.start+hours; hour++ {
+			used[w.row][hour] = true
+		}
+		windows = append(windows, w.FocusWindow)
+	}
+	return windows
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"TomatoList/stats"
+)
+
+// parseStatsRange 解析统计时间范围
+// from、to为用户时区的日期（YYYY-MM-DD，包含两端）；都未提供时使用days参数，表示截至今天的最近N天
+// 出错时已写入响应，返回false
+func parseStatsRange(c *gin.Context, loc *time.Location, defaultDays int) (stats.Range, bool) {
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr == "" && toStr == "" {
+		days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultDays)))
+		if err != nil || days < 1 {
+			days = defaultDays
+		}
+		return stats.LastDays(time.Now(), loc, days), true
+	}
+
+	to := stats.StartOfDay(time.Now(), loc)
+	if toStr != "" {
+		date, err := time.ParseInLocation("2006-01-02", toStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期，格式应为YYYY-MM-DD"})
+			return stats.Range{}, false
+		}
+		to = date
+	}
+
+	from := to.AddDate(0, 0, -(defaultDays - 1))
+	if fromStr != "" {
+		date, err := time.ParseInLocation("2006-01-02", fromStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期，格式应为YYYY-MM-DD"})
+			return stats.Range{}, false
+		}
+		from = date
+	}
+
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期不能晚于结束日期"})
+		return stats.Range{}, false
+	}
+
+	return stats.NewRange(from, to.AddDate(0, 0, 1)), true
+}
+
+// parseWeekStart 解析weekStart参数，默认周一
+// 出错时已写入响应，返回false
+func parseWeekStart(c *gin.Context) (time.Weekday, bool) {
+	weekStart := c.Query("weekStart")
+	if weekStart == "" {
+		return time.Monday, true
+	}
+	day, ok := stats.ParseWeekday(weekStart)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的每周起始日"})
+		return 0, false
+	}
+	return day, true
+}
+
+// parseBucketer 解析granularity和weekStart参数，默认按天、每周从周一开始
+// 出错时已写入响应，返回false
+func parseBucketer(c *gin.Context, loc *time.Location) (stats.Bucketer, bool) {
+	granularity := c.DefaultQuery("granularity", stats.GranularityDay)
+	if !stats.ValidGranularity(granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的统计粒度，可选值：hour、day、week、month、year"})
+		return stats.Bucketer{}, false
+	}
+
+	weekStart, ok := parseWeekStart(c)
+	if !ok {
+		return stats.Bucketer{}, false
+	}
+
+	return stats.Bucketer{Granularity: granularity, WeekStart: weekStart, Location: loc}, true
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// GetTaskSubtree 获取以任务为根的子树
+// 每个任务附带children和progress，progress汇总自身及所有后代的子任务完成情况与番茄钟
+func GetTaskSubtree(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取任务ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	root, err := models.LoadSubtree(db, userID, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取子任务失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, root)
+}
+
+// MoveTask 将任务及其所有后代移动到新的父任务下，parentId为null时移动到顶层
+func MoveTask(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	// 获取任务ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var request struct {
+		ParentID *uint `json:"parentId"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	var task models.Task
+	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&task); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+		}
+		return
+	}
+
+	// 检查循环引用和移动放在同一事务中
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return models.MoveTask(tx, &task, request.ParentID)
+	})
+	switch {
+	case errors.Is(err, models.ErrTaskCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "父任务不存在"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+
+	task.ParentID = request.ParentID
+	task.SetPomodoroProgress(task.CompletedPomodoroCount(db))
+
+	c.JSON(http.StatusOK, task)
+}
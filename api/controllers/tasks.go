@@ -2,13 +2,17 @@
 package controllers
 
 import (
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
 	"TomatoList/models"
+	"TomatoList/stats"
 )
 
 // GetTasks 获取用户的所有任务
@@ -31,12 +35,16 @@ import (
 //
 //	tasks = query.offset(skip).limit(limit).all()
 //	return tasks
+//
+// 传入include=pomodoros时附带每个任务的番茄钟数量和专注时长，整页只执行一次聚合查询；
+// 传入include=forecast时附带未完成任务的完成预测；projectId可按项目过滤，projectId=none返回未分组的任务
 func GetTasks(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	userID := c.MustGet("userID").(uint)
 
 	// 获取查询参数
 	completedStr := c.Query("completed")
+	include := parseInclude(c.Query("include"))
 	pageStr := c.DefaultQuery("page", "1")
 	pageSizeStr := c.DefaultQuery("pageSize", "10")
 
@@ -51,6 +59,18 @@ func GetTasks(c *gin.Context) {
 		}
 	}
 
+	// 按项目过滤
+	if projectIDStr := c.Query("projectId"); projectIDStr == "none" {
+		query = query.Where("project_id IS NULL")
+	} else if projectIDStr != "" {
+		projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+			return
+		}
+		query = query.Where("project_id = ?", projectID)
+	}
+
 	// 分页处理
 	page, _ := strconv.Atoi(pageStr)
 	pageSize, _ := strconv.Atoi(pageSizeStr)
@@ -74,6 +94,15 @@ func GetTasks(c *gin.Context) {
 		return
 	}
 
+	// 一次查询统计整页任务的番茄钟，填充预估与实际进度
+	if err := fillPomodoroStats(db, tasks, include["pomodoros"]); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟统计失败"})
+		return
+	}
+	if include["forecast"] && !fillForecast(c, db, userID, tasks) {
+		return
+	}
+
 	// 返回任务列表和分页信息
 	c.JSON(http.StatusOK, gin.H{
 		"tasks": tasks,
@@ -109,7 +138,74 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, task)
+	tasks := []models.Task{task}
+	include := parseInclude(c.Query("include"))
+	if err := fillPomodoroStats(db, tasks, include["pomodoros"]); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取番茄钟统计失败"})
+		return
+	}
+	if include["forecast"] && !fillForecast(c, db, userID, tasks) {
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks[0])
+}
+
+// parseInclude 解析逗号分隔的include查询参数
+func parseInclude(value string) map[string]bool {
+	include := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			include[name] = true
+		}
+	}
+	return include
+}
+
+// fillPomodoroStats 为任务填充番茄钟进度，withStats为true时同时返回番茄钟汇总
+func fillPomodoroStats(db *gorm.DB, tasks []models.Task, withStats bool) error {
+	taskIDs := make([]uint, len(tasks))
+	for i := range tasks {
+		taskIDs[i] = tasks[i].ID
+	}
+
+	stats, err := models.PomodoroStatsByTask(db, taskIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		taskStats := stats[tasks[i].ID]
+		tasks[i].SetPomodoroProgress(taskStats.CompletedCount)
+		if withStats {
+			tasks[i].PomodoroStats = &taskStats
+		}
+	}
+	return nil
+}
+
+// fillForecast 为未完成的任务填充完成预测，预测基于用户所有未完成任务组成的待办队列
+// 出错时已写入响应，返回false
+func fillForecast(c *gin.Context, db *gorm.DB, userID uint, tasks []models.Task) bool {
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return false
+	}
+
+	forecast, err := stats.NewService(db).Forecast(userID, loc, time.Now(), stats.DefaultVelocityDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取完成预测失败"})
+		return false
+	}
+
+	forecasts := make(map[uint]*models.TaskForecast, len(forecast.Tasks))
+	for _, task := range forecast.Tasks {
+		forecasts[task.ID] = task.Forecast
+	}
+	for i := range tasks {
+		tasks[i].Forecast = forecasts[tasks[i].ID]
+	}
+	return true
 }
 
 // CreateTask 创建新任务
@@ -132,16 +228,41 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
+	if task.EstimatedPomodoros < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "预估番茄钟数量不能为负数"})
+		return
+	}
+
 	// 设置默认优先级
 	if task.Priority == "" {
 		task.Priority = "中"
 	}
 
+	// 项目必须属于当前用户且未归档
+	if task.ProjectID != nil && !checkTaskProject(c, db, userID, *task.ProjectID) {
+		return
+	}
+
+	// 父任务必须属于当前用户
+	if task.ParentID != nil {
+		var parent models.Task
+		if result := db.Select("id").Where("id = ? AND user_id = ?", *task.ParentID, userID).First(&parent); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "父任务不存在"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取父任务失败"})
+			}
+			return
+		}
+	}
+
 	if result := db.Create(&task); result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
 		return
 	}
 
+	task.SetPomodoroProgress(0)
+
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -169,27 +290,108 @@ func UpdateTask(c *gin.Context) {
 	}
 
 	// 绑定更新数据
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	var request map[string]interface{}
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
 		return
 	}
 
-	// 移除不能更新的字段
-	delete(updates, "id")
-	delete(updates, "user_id")
-	delete(updates, "created_at")
+	// 只接受以下字段并映射到数据库列名，其他字段一律忽略
+	// GORM的map更新同时匹配列名和Go字段名（如ParentID、ProjectID），不能直接传入请求数据；
+	// 父任务需通过移动接口修改，以检查循环引用
+	updates := map[string]interface{}{}
+	if value, ok := request["title"]; ok {
+		title, ok := value.(string)
+		if !ok || title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "任务标题不能为空"})
+			return
+		}
+		updates["title"] = title
+	}
+	if value, ok := request["description"]; ok {
+		description, ok := value.(string)
+		if !ok && value != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务描述"})
+			return
+		}
+		updates["description"] = description
+	}
+	if value, ok := request["priority"]; ok {
+		priority, ok := value.(string)
+		if !ok || priority == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的优先级"})
+			return
+		}
+		updates["priority"] = priority
+	}
+	if value, ok := request["completed"]; ok {
+		completed, ok := value.(bool)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的完成状态"})
+			return
+		}
+		updates["completed"] = completed
+	}
+
+	// 截止日期，null表示清除
+	if value, ok := request["dueDate"]; ok {
+		var dueDate time.Time
+		if value != nil {
+			str, ok := value.(string)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的截止日期"})
+				return
+			}
+			if dueDate, err = time.Parse(time.RFC3339, str); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的截止日期"})
+				return
+			}
+		}
+		updates["due_date"] = dueDate
+	}
+
+	// 预估番茄钟数量必须为非负整数
+	if value, ok := request["estimatedPomodoros"]; ok {
+		estimated, ok := value.(float64)
+		if !ok || estimated < 0 || estimated != math.Trunc(estimated) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "预估番茄钟数量必须为非负整数"})
+			return
+		}
+		updates["estimated_pomodoros"] = int(estimated)
+	}
+
+	// 所属项目，null表示移出项目；只接受projectId，以便检查项目归属
+	if value, ok := request["projectId"]; ok {
+		if value == nil {
+			updates["project_id"] = nil
+		} else {
+			projectID, ok := value.(float64)
+			if !ok || projectID < 1 || projectID != math.Trunc(projectID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+				return
+			}
+			if !checkTaskProject(c, db, userID, uint(projectID)) {
+				return
+			}
+			updates["project_id"] = uint(projectID)
+		}
+	}
 
 	// 更新任务
-	if result := db.Model(&existingTask).Updates(updates); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
-		return
+	if len(updates) > 0 {
+		if result := db.Model(&existingTask).Updates(updates); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+			return
+		}
 	}
 
+	existingTask.SetPomodoroProgress(existingTask.CompletedPomodoroCount(db))
+
 	c.JSON(http.StatusOK, existingTask)
 }
 
 // DeleteTask 删除任务
+// 有子任务时必须通过mode参数指定处理方式：cascade同时删除所有后代任务，promote将子任务提升到上一级
 func DeleteTask(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	userID := c.MustGet("userID").(uint)
@@ -201,17 +403,159 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	// 删除任务，并确保属于当前用户
-	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Task{})
-	if result.Error != nil {
+	mode := c.Query("mode")
+	if mode != "" && mode != models.DeleteCascade && mode != models.DeletePromote {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的删除方式，可选值：cascade、promote"})
+		return
+	}
+
+	// 查找任务，并确保属于当前用户
+	var task models.Task
+	if result := db.Where("id = ? AND user_id = ?", id, userID).First(&task); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+		}
+		return
+	}
+
+	var children int64
+	if result := db.Model(&models.Task{}).Where("parent_id = ?", task.ID).Count(&children); result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
 		return
 	}
+	if children > 0 && mode == "" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "任务有子任务，请指定删除方式：cascade或promote",
+			"code":     "task_has_subtasks",
+			"subtasks": children,
+		})
+		return
+	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return models.DeleteTaskTree(tx, &task, mode)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "任务删除成功"})
 }
+
+// GetEstimationAccuracy 获取预估准确度报告
+// 只统计已完成且有预估的任务，按优先级分组比较预估与实际完成的番茄钟数量；
+// ratio为实际/预估，大于1表示低估，小于1表示高估
+func GetEstimationAccuracy(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	var rows []struct {
+		Priority           string
+		EstimatedPomodoros int
+		Actual             int64
+	}
+	result := db.Model(&models.Task{}).
+		Select("tasks.priority, tasks.estimated_pomodoros, COUNT(pomodoros.id) AS actual").
+		Joins("LEFT JOIN pomodoros ON pomodoros.task_id = tasks.id AND pomodoros.kind = ? AND pomodoros.status = ? AND pomodoros.deleted_at IS NULL",
+			models.KindWork, models.StatusCompleted).
+		Where("tasks.user_id = ? AND tasks.completed = ? AND tasks.estimated_pomodoros > 0", userID, true).
+		Group("tasks.id, tasks.priority, tasks.estimated_pomodoros").
+		Scan(&rows)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预估准确度失败"})
+		return
+	}
+
+	type accuracy struct {
+		tasks          int
+		estimated      int64
+		actual         int64
+		ratioSum       float64
+		underestimated int
+		overestimated  int
+		accurate       int
+	}
+	add := func(a *accuracy, estimated int, actual int64) {
+		a.tasks++
+		a.estimated += int64(estimated)
+		a.actual += actual
+		a.ratioSum += float64(actual) / float64(estimated)
+		switch {
+		case actual > int64(estimated):
+			a.underestimated++
+		case actual < int64(estimated):
+			a.overestimated++
+		default:
+			a.accurate++
+		}
+	}
+	summarize := func(a *accuracy) gin.H {
+		summary := gin.H{
+			"tasks":          a.tasks,
+			"estimated":      a.estimated,
+			"actual":         a.actual,
+			"averageRatio":   nil,
+			"underestimated": a.underestimated,
+			"overestimated":  a.overestimated,
+			"accurate":       a.accurate,
+		}
+		if a.tasks > 0 {
+			summary["averageRatio"] = math.Round(a.ratioSum/float64(a.tasks)*100) / 100
+		}
+		return summary
+	}
+
+	var overall accuracy
+	byPriority := map[string]*accuracy{}
+	for _, row := range rows {
+		add(&overall, row.EstimatedPomodoros, row.Actual)
+		if byPriority[row.Priority] == nil {
+			byPriority[row.Priority] = &accuracy{}
+		}
+		add(byPriority[row.Priority], row.EstimatedPomodoros, row.Actual)
+	}
+
+	priorityStats := []gin.H{}
+	for _, priority := range []string{"高", "中", "低"} {
+		if a := byPriority[priority]; a != nil {
+			stat := summarize(a)
+			stat["priority"] = priority
+			priorityStats = append(priorityStats, stat)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"overall":    summarize(&overall),
+		"byPriority": priorityStats,
+	})
+}
+
+// GetTaskForecast 获取未完成任务的完成预测
+// 根据最近days天（默认28天）平均每天完成的番茄钟数量和任务的剩余预估，预测每个任务的完成日期；
+// 预计完成日期晚于截止日期或已过期的任务标记为有逾期风险
+func GetTaskForecast(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID := c.MustGet("userID").(uint)
+
+	loc, ok := requestLocation(c, db, userID)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(stats.DefaultVelocityDays)))
+	if err != nil || days < 1 || days > stats.MaxVelocityDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": stats.ErrInvalidVelocityDays.Error()})
+		return
+	}
+
+	forecast, err := stats.NewService(db).Forecast(userID, loc, time.Now(), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取完成预测失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// requestLocation 获取统计使用的时区：优先使用tz查询参数，否则使用用户资料中的时区
+// 出错时已写入响应，返回false
+func requestLocation(c *gin.Context, db *gorm.DB, userID uint) (*time.Location, bool) {
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := models.LoadTimezone(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return loc, true
+	}
+
+	loc, err := models.GetUserLocation(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户时区失败"})
+		return nil, false
+	}
+	return loc, true
+}
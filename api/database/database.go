@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
@@ -32,9 +33,24 @@ func InitDatabase() {
 	var err error
 	var dialect gorm.Dialector
 
-	// PostgreSQL连接
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", "127.0.0.1", "postgres", "postgresql-pwd", "tomato-list", "5432")
-	dialect = postgres.Open(dsn)
+	// 根据环境变量选择数据库：DB_DRIVER为postgres（默认）或sqlite，DB_DSN为连接字符串
+	dsn := os.Getenv("DB_DSN")
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", "postgres":
+		// PostgreSQL连接
+		if dsn == "" {
+			dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", "127.0.0.1", "postgres", "postgresql-pwd", "tomato-list", "5432")
+		}
+		dialect = postgres.Open(dsn)
+	case "sqlite":
+		// SQLite连接，需要开启外键约束
+		if dsn == "" {
+			dsn = "tomato-list.db?_fk=1"
+		}
+		dialect = sqlite.Open(dsn)
+	default:
+		log.Fatal("Unsupported database driver: ", driver)
+	}
 
 	// 配置GORM日志
 	newLogger := logger.New(
@@ -49,7 +65,8 @@ func InitDatabase() {
 
 	// 连接数据库
 	DB, err = gorm.Open(dialect, &gorm.Config{
-		Logger: newLogger,
+		Logger:         newLogger,
+		TranslateError: true, // 将唯一约束冲突等错误转换为gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
@@ -58,13 +75,36 @@ func InitDatabase() {
 	// 自动迁移模型
 	err = DB.AutoMigrate(
 		&models.User{},
+		&models.Project{},
 		&models.Task{},
 		&models.Pomodoro{},
+		&models.PomodoroPause{},
+		&models.PomodoroRevision{},
+		&models.Distraction{},
+		&models.TimerSettings{},
+		&models.GoalChange{},
+		&models.DailyRollup{},
+		&models.ShareToken{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// 将SQLite中以本地时区偏移保存的番茄钟时间转换为UTC
+	if err := normalizeSQLiteTimes(DB); err != nil {
+		log.Fatal("Failed to normalize times:", err)
+	}
+
+	// 创建自动迁移无法表达的索引
+	if err := createIndexes(DB); err != nil {
+		log.Fatal("Failed to create indexes:", err)
+	}
+
+	// 补全历史记录的净专注时长
+	if err := backfillFocusedSeconds(DB); err != nil {
+		log.Fatal("Failed to backfill focused seconds:", err)
+	}
+
 	log.Println("Database connected successfully")
 }
 
@@ -72,3 +112,77 @@ func InitDatabase() {
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// backfillFocusedSeconds 为引入暂停功能之前结束的番茄钟补全净专注时长
+// 这些记录没有暂停片段，净专注时长即结束时间与开始时间之差
+func backfillFocusedSeconds(db *gorm.DB) error {
+	var pomodoros []models.Pomodoro
+	return db.Preload("Pauses").
+		Where("focused_seconds = 0 AND status IN ? AND end_time > start_time", []string{models.StatusCompleted, models.StatusInterrupted}).
+		FindInBatches(&pomodoros, 500, func(tx *gorm.DB, batch int) error {
+			for i := range pomodoros {
+				seconds := int(pomodoros[i].NetDuration(pomodoros[i].EndTime).Seconds())
+				if err := tx.Model(&pomodoros[i]).UpdateColumn("focused_seconds", seconds).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// sqliteTimeColumns 需要按时间范围比较的列
+var sqliteTimeColumns = map[string][]string{
+	"pomodoros":       {"start_time", "end_time", "expected_end_time"},
+	"pomodoro_pauses": {"start_time", "end_time"},
+}
+
+// normalizeSQLiteTimes 将SQLite中带非零时区偏移的时间转换为UTC
+// SQLite以字符串保存时间并按字符串比较，偏移不同的时间无法正确比较范围；
+// 之前写入的时间使用服务器本地时区，现在统一以UTC保存，PostgreSQL的timestamptz不受影响
+func normalizeSQLiteTimes(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+	for table, columns := range sqliteTimeColumns {
+		for _, column := range columns {
+			err := db.Exec("UPDATE " + table + " SET " + column + " = strftime('%Y-%m-%d %H:%M:%f', " + column + ") || '+00:00' " +
+				"WHERE substr(" + column + ", -6) GLOB '[+-][0-9][0-9]:[0-9][0-9]' AND substr(" + column + ", -6) <> '+00:00'").Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createIndexes 创建部分唯一索引，保证每个用户最多只有一个活动（进行中或已暂停）的番茄钟
+// PostgreSQL和SQLite均支持部分索引
+func createIndexes(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&models.Pomodoro{}, "idx_pomodoros_user_active") {
+		return nil
+	}
+
+	// 建索引前将重复的活动番茄钟标记为已过期，只保留每个用户最近开始的一个
+	// 开始时间相同时保留ID较大的一个，避免全部保留或全部过期
+	var duplicates []models.Pomodoro
+	err := db.Where("status IN ? AND EXISTS (SELECT 1 FROM pomodoros newer WHERE newer.user_id = pomodoros.user_id "+
+		"AND newer.status IN ? AND newer.deleted_at IS NULL AND (newer.start_time > pomodoros.start_time "+
+		"OR (newer.start_time = pomodoros.start_time AND newer.id > pomodoros.id)))",
+		models.ActiveStatuses, models.ActiveStatuses).
+		Find(&duplicates).Error
+	if err != nil {
+		return err
+	}
+	for i := range duplicates {
+		updates := map[string]interface{}{
+			"status":   models.StatusExpired,
+			"end_time": duplicates[i].ExpectedEndTime,
+		}
+		if err := db.Model(&duplicates[i]).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	return db.Exec("CREATE UNIQUE INDEX idx_pomodoros_user_active ON pomodoros (user_id) " +
+		"WHERE status IN ('" + models.StatusRunning + "', '" + models.StatusPaused + "') AND deleted_at IS NULL").Error
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// 番茄钟生命周期事件类型
+const (
+	PomodoroStarted     = "started"
+	PomodoroPaused      = "paused"
+	PomodoroResumed     = "resumed"
+	PomodoroCompleted   = "completed"
+	PomodoroInterrupted = "interrupted"
+	PomodoroExpired     = "expired"
+	PomodoroUpdated     = "updated" // 历史记录被修正
+	PomodoroDeleted     = "deleted"
+
+	// Resync 客户端的Last-Event-ID已超出保留的历史范围，需要重新拉取完整状态
+	Resync = "resync"
+)
+
+// Event 推送给客户端的事件
+type Event struct {
+	ID   uint64      `json:"id"`   // 事件ID，单调递增
+	Type string      `json:"type"` // 事件类型
+	Data interface{} `json:"data"` // 事件数据（如番茄钟记录）
+	Time time.Time   `json:"time"` // 事件发生时间
+}
+
+// subscriberBuffer 每个订阅者的缓冲区大小，缓冲区满时断开该订阅者，由客户端重连补发
+const subscriberBuffer = 16
+
+// Hub 按用户分发事件，并为断线重连保留每个用户最近的事件
+type Hub struct {
+	mu          sync.Mutex
+	startID     uint64 // 本次启动时的起始ID，更早的事件已无法补发
+	nextID      uint64
+	historySize int
+	history     map[uint][]Event
+	trimmed     map[uint]uint64 // 每个用户已从历史中丢弃的最大事件ID
+	subscribers map[uint]map[chan Event]struct{}
+	closed      bool
+}
+
+// NewHub 创建事件中心，historySize为每个用户保留的历史事件数量
+func NewHub(historySize int) *Hub {
+	// 以启动时间作为起始ID，保证服务重启后事件ID仍然递增
+	startID := uint64(time.Now().UnixMilli()) * 1000
+	return &Hub{
+		startID:     startID,
+		nextID:      startID,
+		historySize: historySize,
+		history:     make(map[uint][]Event),
+		trimmed:     make(map[uint]uint64),
+		subscribers: make(map[uint]map[chan Event]struct{}),
+	}
+}
+
+// Publish 向用户的所有订阅者发布事件
+func (h *Hub) Publish(userID uint, eventType string, data interface{}) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	event := Event{ID: h.nextID, Type: eventType, Data: data, Time: time.Now()}
+	if h.closed {
+		return event
+	}
+
+	// 保存历史事件
+	history := append(h.history[userID], event)
+	if len(history) > h.historySize {
+		drop := len(history) - h.historySize
+		h.trimmed[userID] = history[drop-1].ID
+		history = history[drop:]
+	}
+	h.history[userID] = history
+
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者处理过慢，断开连接让客户端携带Last-Event-ID重连
+			h.removeLocked(userID, ch)
+		}
+	}
+
+	return event
+}
+
+// Subscribe 订阅用户的事件
+// lastEventID不为0时返回该ID之后需要补发的历史事件；若该ID已超出保留范围，补发一个Resync事件
+// 调用方使用完毕后必须调用cancel
+func (h *Hub) Subscribe(userID uint, lastEventID uint64) (ch <-chan Event, backlog []Event, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := make(chan Event, subscriberBuffer)
+	if h.closed {
+		close(sub)
+		return sub, nil, func() {}
+	}
+
+	if lastEventID != 0 {
+		backlog = h.backlogLocked(userID, lastEventID)
+	}
+
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.removeLocked(userID, sub)
+	}
+	return sub, backlog, cancel
+}
+
+// Close 关闭所有订阅，用于服务器关闭时结束长连接
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for userID, subs := range h.subscribers {
+		for ch := range subs {
+			h.removeLocked(userID, ch)
+		}
+	}
+}
+
+// backlogLocked 返回lastEventID之后的历史事件，调用方需持有锁
+func (h *Hub) backlogLocked(userID uint, lastEventID uint64) []Event {
+	// 需要的事件发生在服务重启之前、已被丢弃，或ID无法识别时，无法可靠补发
+	if lastEventID < h.startID || lastEventID < h.trimmed[userID] || lastEventID > h.nextID {
+		return []Event{{ID: h.nextID, Type: Resync, Time: time.Now()}}
+	}
+
+	var backlog []Event
+	for _, event := range h.history[userID] {
+		if event.ID > lastEventID {
+			backlog = append(backlog, event)
+		}
+	}
+	return backlog
+}
+
+// removeLocked 移除并关闭订阅者，调用方需持有锁
+func (h *Hub) removeLocked(userID uint, ch chan Event) {
+	subs := h.subscribers[userID]
+	if _, ok := subs[ch]; !ok {
+		return
+	}
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(h.subscribers, userID)
+	}
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+// publishN 向用户发布n个事件，返回发布的事件
+func publishN(h *Hub, userID uint, n int) []Event {
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = h.Publish(userID, PomodoroUpdated, i)
+	}
+	return events
+}
+
+func eventIDs(events []Event) []uint64 {
+	ids := make([]uint64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func TestSubscribeReplaysAfterLastEventID(t *testing.T) {
+	h := NewHub(10)
+	published := publishN(h, 1, 3)
+	publishN(h, 2, 2) // 其他用户的事件不补发
+
+	_, backlog, cancel := h.Subscribe(1, published[0].ID)
+	defer cancel()
+	if got, want := eventIDs(backlog), eventIDs(published[1:]); !reflect.DeepEqual(got, want) {
+		t.Errorf("backlog = %v, want %v", got, want)
+	}
+
+	// 已收到最新事件时不需要补发
+	_, backlog, cancel2 := h.Subscribe(1, published[2].ID)
+	defer cancel2()
+	if len(backlog) != 0 {
+		t.Errorf("backlog = %v, want empty", eventIDs(backlog))
+	}
+
+	// 首次连接不补发
+	_, backlog, cancel3 := h.Subscribe(1, 0)
+	defer cancel3()
+	if backlog != nil {
+		t.Errorf("backlog = %v, want nil", eventIDs(backlog))
+	}
+}
+
+func TestSubscribeReceivesLiveEvents(t *testing.T) {
+	h := NewHub(10)
+	ch, _, cancel := h.Subscribe(1, 0)
+	defer cancel()
+
+	h.Publish(2, PomodoroStarted, nil)
+	event := h.Publish(1, PomodoroStarted, nil)
+	select {
+	case got := <-ch:
+		if got.ID != event.ID {
+			t.Errorf("received event %d, want %d", got.ID, event.ID)
+		}
+	default:
+		t.Fatal("no event received")
+	}
+}
+
+func TestSubscribeResyncAfterTrim(t *testing.T) {
+	h := NewHub(2)
+	published := publishN(h, 1, 4) // 只保留最后两个事件
+
+	_, backlog, cancel := h.Subscribe(1, published[0].ID)
+	defer cancel()
+	if len(backlog) != 1 || backlog[0].Type != Resync {
+		t.Errorf("backlog = %+v, want a single resync event", backlog)
+	}
+
+	// 客户端收到的最后一个事件恰好是最后丢弃的事件，之后的事件都还保留
+	_, backlog, cancel2 := h.Subscribe(1, published[1].ID)
+	defer cancel2()
+	if got, want := eventIDs(backlog), eventIDs(published[2:]); !reflect.DeepEqual(got, want) {
+		t.Errorf("backlog = %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeResyncOnUnknownID(t *testing.T) {
+	h := NewHub(10)
+	published := publishN(h, 1, 2)
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+	}{
+		{"before restart", h.startID - 1},
+		{"too new", published[1].ID + 1},
+	}
+	for _, tt := range tests {
+		_, backlog, cancel := h.Subscribe(1, tt.lastEventID)
+		cancel()
+		if len(backlog) != 1 || backlog[0].Type != Resync {
+			t.Errorf("%s: backlog = %+v, want a single resync event", tt.name, backlog)
+			continue
+		}
+		// Resync事件的ID为当前最新ID，客户端重新拉取状态后以此为Last-Event-ID
+		if backlog[0].ID != published[1].ID {
+			t.Errorf("%s: resync id = %d, want %d", tt.name, backlog[0].ID, published[1].ID)
+		}
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	h := NewHub(100)
+	ch, _, cancel := h.Subscribe(1, 0)
+	defer cancel()
+
+	publishN(h, 1, subscriberBuffer+1)
+
+	// 缓冲区中的事件仍可读取，之后通道被关闭
+	received := 0
+	for range ch {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("received %d events, want %d", received, subscriberBuffer)
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	h := NewHub(10)
+	ch, _, cancel := h.Subscribe(1, 0)
+	defer cancel()
+
+	h.Close()
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after Close")
+	}
+	if ch, _, _ := h.Subscribe(1, 0); ch == nil {
+		t.Error("Subscribe after Close returned nil channel")
+	} else if _, ok := <-ch; ok {
+		t.Error("Subscribe after Close returned an open channel")
+	}
+}
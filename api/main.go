@@ -2,11 +2,21 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // 内嵌时区数据库，容器中缺少系统时区数据时仍可解析用户时区
 
 	"TomatoList/controllers"
 	"TomatoList/database"
+	"TomatoList/events"
 	"TomatoList/middleware"
+	"TomatoList/models"
+	"TomatoList/workers"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,12 +29,17 @@ func main() {
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode) // 生产环境使用ReleaseMode
 
-	// 创建Gin路由器
-	router := gin.Default()
+	// 创建Gin路由器，访问日志隐藏查询参数中的令牌
+	router := gin.New()
+	router.Use(middleware.Logger(), gin.Recovery())
+
+	// 事件中心：向同一用户的所有客户端推送番茄钟事件，每个用户保留最近100条用于断线补发
+	hub := events.NewHub(100)
 
 	// 中间件
 	router.Use(middleware.CORS())                 // CORS中间件
 	router.Use(middleware.DatabaseMiddleware(db)) // 数据库中间件
+	router.Use(middleware.EventsMiddleware(hub))  // 事件中心中间件
 
 	// API路由
 	api := router.Group("/api")
@@ -36,28 +51,105 @@ func main() {
 			auth.POST("/login", controllers.Login)
 		}
 
+		// 事件流（允许通过查询参数传递令牌）
+		api.GET("/pomodoros/events", middleware.QueryJWTAuth(), controllers.StreamPomodoroEvents)
+
+		// 可嵌入的图片，使用只读分享令牌认证
+		api.GET("/pomodoros/stats/calendar.svg", middleware.ShareTokenAuth(models.ShareScopeCalendar), controllers.GetFocusCalendarSVG)
+
 		// 需要认证的路由
 		authorized := api.Group("/")
 		authorized.Use(middleware.JWTAuth()) // JWT认证中间件
 		{
 			// 任务路由
 			authorized.GET("/tasks", controllers.GetTasks)
+			authorized.GET("/tasks/estimates", controllers.GetEstimationAccuracy)
+			authorized.GET("/tasks/forecast", controllers.GetTaskForecast)
 			authorized.GET("/tasks/:id", controllers.GetTask)
+			authorized.GET("/tasks/:id/subtree", controllers.GetTaskSubtree)
+			authorized.POST("/tasks/:id/move", controllers.MoveTask)
 			authorized.POST("/tasks", controllers.CreateTask)
 			authorized.PUT("/tasks/:id", controllers.UpdateTask)
 			authorized.DELETE("/tasks/:id", controllers.DeleteTask)
 
+			// 项目路由
+			authorized.GET("/projects", controllers.GetProjects)
+			authorized.POST("/projects", controllers.CreateProject)
+			authorized.PUT("/projects/:id", controllers.UpdateProject)
+			authorized.DELETE("/projects/:id", controllers.DeleteProject)
+
 			// 番茄钟路由
 			authorized.POST("/pomodoros", controllers.StartPomodoro)
+			authorized.POST("/pomodoros/manual", controllers.LogManualPomodoro)
 			authorized.POST("/pomodoros/:id/complete", controllers.CompletePomodoro)
+			authorized.POST("/pomodoros/:id/interrupt", controllers.InterruptPomodoro)
+			authorized.POST("/pomodoros/:id/pause", controllers.PausePomodoro)
+			authorized.POST("/pomodoros/:id/resume", controllers.ResumePomodoro)
 			authorized.GET("/pomodoros", controllers.GetPomodoros)
+			authorized.GET("/pomodoros/current", controllers.GetCurrentPomodoro)
+			authorized.GET("/pomodoros/next", controllers.GetNextSession)
 			authorized.GET("/pomodoros/stats", controllers.GetPomodoroStats)
+			authorized.GET("/pomodoros/stats/focus", controllers.GetFocusStats)
+			authorized.GET("/pomodoros/stats/heatmap", controllers.GetFocusHeatmap)
+			authorized.GET("/pomodoros/stats/calendar", controllers.GetFocusCalendar)
+			authorized.PUT("/pomodoros/:id", controllers.UpdatePomodoro)
+			authorized.DELETE("/pomodoros/:id", controllers.DeletePomodoro)
+			authorized.GET("/pomodoros/:id/history", controllers.GetPomodoroHistory)
+			authorized.POST("/pomodoros/:id/distractions", controllers.CreateDistraction)
+			authorized.GET("/pomodoros/:id/distractions", controllers.GetDistractions)
+
+			// 用户资料路由
+			authorized.GET("/profile", controllers.GetProfile)
+			authorized.PUT("/profile", controllers.UpdateProfile)
+
+			// 每日目标路由
+			authorized.GET("/goals", controllers.GetGoal)
+			authorized.PUT("/goals", controllers.UpdateGoal)
+
+			// 设置路由
+			authorized.GET("/settings/timer", controllers.GetTimerSettings)
+			authorized.PUT("/settings/timer", controllers.UpdateTimerSettings)
+
+			// 分享令牌路由
+			authorized.GET("/share-tokens", controllers.GetShareTokens)
+			authorized.POST("/share-tokens", controllers.CreateShareToken)
+			authorized.DELETE("/share-tokens/:id", controllers.DeleteShareToken)
 		}
 	}
 
+	// 启动后台清理任务：每分钟扫描一次，超过预期结束时间5分钟仍未结束的番茄钟按用户策略处理，
+	// 暂停超过2小时的番茄钟标记为已过期
+	sweeper := workers.NewSweeper(db, hub, time.Minute, 5*time.Minute, 2*time.Hour)
+	sweeper.Start()
+
 	// 启动服务器
-	log.Println("Server starting on :8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: router,
+	}
+
+	go func() {
+		log.Println("Server starting on :8080")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// 等待中断信号，优雅关闭服务器和后台任务
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	// 先关闭事件流长连接，否则Shutdown会一直等待它们结束
+	hub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Server forced to shutdown:", err)
 	}
+	sweeper.Stop()
+
+	log.Println("Server exited")
 }
@@ -2,20 +2,37 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"TomatoList/events"
+	"TomatoList/models"
 	"TomatoList/utils"
 )
 
 // JWTAuth JWT认证中间件
 func JWTAuth() gin.HandlerFunc {
+	return jwtAuth(false)
+}
+
+// QueryJWTAuth 允许通过access_token查询参数传递令牌的JWT认证中间件
+// 用于浏览器的EventSource等无法设置请求头的场景
+func QueryJWTAuth() gin.HandlerFunc {
+	return jwtAuth(true)
+}
+
+func jwtAuth(allowQuery bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从Authorization头部获取token
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && allowQuery && c.Query("access_token") != "" {
+			authHeader = "Bearer " + c.Query("access_token")
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少认证令牌"})
 			c.Abort()
@@ -44,6 +61,38 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// ShareTokenAuth 只读分享令牌认证中间件，令牌通过token查询参数传递
+// 用于嵌入到团队wiki等页面的图片链接，令牌只能访问scope对应的接口，可随时撤销，不会泄露登录令牌
+// 需要在DatabaseMiddleware之后使用
+func ShareTokenAuth(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("token")
+		if raw == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少分享令牌"})
+			c.Abort()
+			return
+		}
+
+		db := c.MustGet("db").(*gorm.DB)
+		token, err := models.FindShareToken(db, raw, scope)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的分享令牌"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "验证分享令牌失败"})
+			}
+			c.Abort()
+			return
+		}
+
+		// 记录最后使用时间，失败不影响访问
+		db.Model(token).UpdateColumn("last_used_at", time.Now().UTC())
+
+		c.Set("userID", token.UserID)
+		c.Next()
+	}
+}
+
 // DatabaseMiddleware 数据库中间件，将数据库连接注入到上下文
 func DatabaseMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -51,3 +100,11 @@ func DatabaseMiddleware(db *gorm.DB) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// EventsMiddleware 事件中心中间件，将事件中心注入到上下文
+func EventsMiddleware(hub *events.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("events", hub)
+		c.Next()
+	}
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DailyRollup 番茄钟的每日汇总，按用户时区的日期、任务、类型、状态、是否补录和中断原因分组
+// 只汇总已结束的番茄钟，由修改番茄钟的同一事务重新计算对应日期，统计接口直接读取汇总而不扫描原始记录
+// 与Python SQLAlchemy对比：
+// # class DailyRollup(Base):
+// #     __tablename__ = "pomodoro_daily_rollups"
+// #     __table_args__ = (UniqueConstraint("user_id", "date", "task_id", "kind", "status", "is_manual", "interrupt_reason"),)
+// #     id = Column(Integer, primary_key=True)
+// #     user_id = Column(Integer, ForeignKey("users.id"), nullable=False)
+// #     date = Column(String(10), nullable=False)
+// #     task_id = Column(Integer, nullable=False, default=0)
+// #     count = Column(Integer, nullable=False, default=0)
+// #     focused_seconds = Column(Integer, nullable=False, default=0)
+type DailyRollup struct {
+	ID              uint   `json:"id" gorm:"primaryKey"`
+	UserID          uint   `json:"userId" gorm:"not null;uniqueIndex:idx_daily_rollups_key,priority:1"`                                      // 关联的用户ID
+	Date            string `json:"date" gorm:"type:varchar(10);not null;uniqueIndex:idx_daily_rollups_key,priority:2"`                       // 用户时区的日期（YYYY-MM-DD）
+	TaskID          uint   `json:"taskId" gorm:"not null;default:0;uniqueIndex:idx_daily_rollups_key,priority:3"`                            // 关联的任务ID，0表示未关联任务
+	Kind            string `json:"kind" gorm:"type:varchar(20);not null;uniqueIndex:idx_daily_rollups_key,priority:4"`                       // 时段类型
+	Status          string `json:"status" gorm:"type:varchar(20);not null;uniqueIndex:idx_daily_rollups_key,priority:5"`                     // 状态
+	IsManual        bool   `json:"isManual" gorm:"not null;uniqueIndex:idx_daily_rollups_key,priority:6"`                                    // 是否手动补录
+	InterruptReason string `json:"interruptReason" gorm:"type:varchar(20);not null;default:'';uniqueIndex:idx_daily_rollups_key,priority:7"` // 中断原因
+	Count           int64  `json:"count" gorm:"not null;default:0"`                                                                          // 番茄钟数量
+	FocusedSeconds  int64  `json:"focusedSeconds" gorm:"not null;default:0"`                                                                 // 净专注秒数
+}
+
+// TableName 指定表名
+func (DailyRollup) TableName() string {
+	return "pomodoro_daily_rollups"
+}
+
+// RefreshDailyRollups 重新计算用户在指定时刻所在日期（用户时区）的汇总
+// 需要在修改番茄钟的同一事务中调用；修改开始时间时应同时传入修改前后的开始时间
+// 先锁定用户行，同一用户的并发刷新依次执行，避免先删后插时唯一索引冲突
+func RefreshDailyRollups(tx *gorm.DB, userID uint, times ...time.Time) error {
+	if err := LockUser(tx, userID); err != nil {
+		return err
+	}
+	loc, err := GetUserLocation(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	refreshed := make(map[string]bool, len(times))
+	for _, t := range times {
+		local := t.In(loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		date := day.Format("2006-01-02")
+		if refreshed[date] {
+			continue
+		}
+		refreshed[date] = true
+
+		if err := rebuildRollups(tx, userID, loc, day, day.AddDate(0, 0, 1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildUserRollups 重建用户的全部每日汇总，用于补全历史数据和用户修改时区之后
+func RebuildUserRollups(tx *gorm.DB, userID uint) error {
+	if err := LockUser(tx, userID); err != nil {
+		return err
+	}
+	loc, err := GetUserLocation(tx, userID)
+	if err != nil {
+		return err
+	}
+	return rebuildRollups(tx, userID, loc, time.Time{}, time.Time{})
+}
+
+// rebuildRollups 删除并重新计算[from, to)内开始的番茄钟的汇总，from为零值时重建全部
+func rebuildRollups(tx *gorm.DB, userID uint, loc *time.Location, from, to time.Time) error {
+	query := tx.Model(&Pomodoro{}).
+		Select("start_time, task_id, kind, status, is_manual, interrupt_reason, focused_seconds").
+		Where("user_id = ? AND status NOT IN ?", userID, ActiveStatuses)
+	clear := tx.Where("user_id = ?", userID)
+	if !from.IsZero() {
+		// 开始时间以UTC保存，范围同样以UTC绑定
+		query = query.Where("start_time >= ? AND start_time < ?", from.UTC(), to.UTC())
+		clear = clear.Where("date = ?", from.Format("2006-01-02"))
+	}
+
+	var sessions []struct {
+		StartTime       time.Time
+		TaskID          *uint
+		Kind            string
+		Status          string
+		IsManual        bool
+		InterruptReason string
+		FocusedSeconds  int64
+	}
+	if err := query.Scan(&sessions).Error; err != nil {
+		return err
+	}
+
+	if err := clear.Delete(&DailyRollup{}).Error; err != nil {
+		return err
+	}
+
+	// 按唯一键聚合
+	index := make(map[DailyRollup]int)
+	var rollups []DailyRollup
+	for _, session := range sessions {
+		key := DailyRollup{
+			UserID:          userID,
+			Date:            session.StartTime.In(loc).Format("2006-01-02"),
+			Kind:            session.Kind,
+			Status:          session.Status,
+			IsManual:        session.IsManual,
+			InterruptReason: session.InterruptReason,
+		}
+		if session.TaskID != nil {
+			key.TaskID = *session.TaskID
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(rollups)
+			index[key] = i
+			rollups = append(rollups, key)
+		}
+		rollups[i].Count++
+		rollups[i].FocusedSeconds += session.FocusedSeconds
+	}
+
+	if len(rollups) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rollups, 500).Error
+}
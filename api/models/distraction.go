@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 干扰类型
+const (
+	DistractionInternal = "internal" // 内部干扰：走神、突然想到别的事
+	DistractionExternal = "external" // 外部干扰：消息、电话、他人打断
+)
+
+// ValidDistractionType 检查干扰类型是否有效
+func ValidDistractionType(distractionType string) bool {
+	return distractionType == DistractionInternal || distractionType == DistractionExternal
+}
+
+// Distraction 番茄钟期间的干扰记录模型
+// 与Python SQLAlchemy对比：
+// # class Distraction(Base):
+// #     __tablename__ = "distractions"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     pomodoro_id = Column(Integer, ForeignKey("pomodoros.id"), index=True)
+// #     user_id = Column(Integer, ForeignKey("users.id"))
+// #     type = Column(String, nullable=False)  # internal, external
+// #     note = Column(Text)
+// #     occurred_at = Column(DateTime, nullable=False)
+type Distraction struct {
+	gorm.Model
+	PomodoroID uint      `json:"pomodoroId" gorm:"not null;index"` // 关联的番茄钟ID
+	UserID     uint      `json:"userId" gorm:"not null"`           // 关联的用户ID
+	Type       string    `json:"type" gorm:"not null"`             // 干扰类型：internal、external
+	Note       string    `json:"note"`                             // 备注
+	OccurredAt time.Time `json:"occurredAt" gorm:"not null"`       // 发生时间
+}
+
+// TableName 指定表名
+func (Distraction) TableName() string {
+	return "distractions"
+}
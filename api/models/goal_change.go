@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 每日目标范围，0表示未设置目标
+const (
+	MinDailyGoal = 0
+	MaxDailyGoal = 48
+)
+
+// GoalChange 每日番茄钟目标的变更记录
+// 目标从变更时刻起生效，历史日期按当天结束时生效的目标判断是否达成
+// 与Python SQLAlchemy对比：
+// # class GoalChange(Base):
+// #     __tablename__ = "goal_changes"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     user_id = Column(Integer, ForeignKey("users.id"), index=True)
+// #     daily_goal = Column(Integer, nullable=False)
+// #     effective_from = Column(DateTime, nullable=False)
+type GoalChange struct {
+	gorm.Model
+	UserID        uint      `json:"userId" gorm:"not null;index"`  // 关联的用户ID
+	DailyGoal     int       `json:"dailyGoal" gorm:"not null"`     // 每日完成的工作时段数量目标
+	EffectiveFrom time.Time `json:"effectiveFrom" gorm:"not null"` // 生效时间
+}
+
+// TableName 指定表名
+func (GoalChange) TableName() string {
+	return "goal_changes"
+}
+
+// ValidateDailyGoal 验证每日目标
+func ValidateDailyGoal(goal int) error {
+	if goal < MinDailyGoal || goal > MaxDailyGoal {
+		return errors.New("每日目标必须在0到48之间")
+	}
+	return nil
+}
+
+// GoalTimeline 按生效时间升序排列的目标变更记录
+type GoalTimeline []GoalChange
+
+// GetGoalTimeline 查询用户的所有目标变更记录
+func GetGoalTimeline(db *gorm.DB, userID uint) (GoalTimeline, error) {
+	var changes []GoalChange
+	err := db.Where("user_id = ?", userID).Order("effective_from, id").Find(&changes).Error
+	return GoalTimeline(changes), err
+}
+
+// GoalAt 返回指定时刻之前最后一次变更的目标，没有变更记录时返回0
+func (t GoalTimeline) GoalAt(at time.Time) int {
+	goal := 0
+	for _, change := range t {
+		if !change.EffectiveFrom.Before(at) {
+			break
+		}
+		goal = change.DailyGoal
+	}
+	return goal
+}
+
+// Current 返回当前生效的目标
+func (t GoalTimeline) Current() int {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].DailyGoal
+}
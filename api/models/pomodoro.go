@@ -2,6 +2,8 @@
 package models
 
 import (
+	"errors"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,20 +19,75 @@ import (
 // #     start_time = Column(DateTime, nullable=False)
 // #     end_time = Column(DateTime)
 // #     expected_end_time = Column(DateTime, nullable=False)
-// #     status = Column(String, default="进行中")  # 进行中, 已完成, 已中断
+// #     kind = Column(String, default="工作")  # 工作, 短休息, 长休息
+// #     status = Column(String, default="进行中")  # 进行中, 已暂停, 已完成, 已中断, 已过期
+// #     interrupt_reason = Column(String)  # internal_distraction, external_distraction, meeting, other
+// #     interrupt_note = Column(Text)
+// #     focused_seconds = Column(Integer, default=0)  # 扣除暂停后的净专注时长
+// #     is_manual = Column(Boolean, default=False)  # 事后手动补录
+// #     notes = Column(Text)  # 复盘记录
+// #     focus_rating = Column(Integer)  # 1-5
+// #     mood = Column(Integer)  # 1-5
 // #     created_at = Column(DateTime, default=datetime.utcnow)
 type Pomodoro struct {
 	gorm.Model
-	TaskID          uint      `json:"taskId" gorm:"not null"`          // 关联的任务ID
-	UserID          uint      `json:"userId" gorm:"not null"`          // 关联的用户ID
-	StartTime       time.Time `json:"startTime" gorm:"not null"`       // 开始时间
-	EndTime         time.Time `json:"endTime"`                         // 结束时间（实际结束时间）
-	ExpectedEndTime time.Time `json:"expectedEndTime" gorm:"not null"` // 预期结束时间
-	Status          string    `json:"status" gorm:"default:'进行中'"`     // 状态：进行中、已完成、已中断
+	TaskID          *uint     `json:"taskId"`                                   // 关联的任务ID（休息时段可为空）
+	UserID          uint      `json:"userId" gorm:"not null"`                   // 关联的用户ID
+	Kind            string    `json:"kind" gorm:"not null;default:'工作'"`        // 类型：工作、短休息、长休息
+	StartTime       time.Time `json:"startTime" gorm:"not null"`                // 开始时间
+	EndTime         time.Time `json:"endTime"`                                  // 结束时间（实际结束时间）
+	ExpectedEndTime time.Time `json:"expectedEndTime" gorm:"not null"`          // 预期结束时间
+	Status          string    `json:"status" gorm:"default:'进行中'"`              // 状态：进行中、已暂停、已完成、已中断、已过期
+	InterruptReason string    `json:"interruptReason,omitempty"`                // 中断原因代码
+	InterruptNote   string    `json:"interruptNote,omitempty"`                  // 中断备注
+	FocusedSeconds  int       `json:"focusedSeconds" gorm:"not null;default:0"` // 净专注时长（秒，扣除暂停），结束时写入
+	IsManual        bool      `json:"isManual" gorm:"not null;default:false"`   // 是否为事后手动补录
+	Notes           string    `json:"notes,omitempty" gorm:"type:text"`         // 复盘：完成了什么
+	FocusRating     int       `json:"focusRating,omitempty" gorm:"default:0"`   // 复盘：专注度评分1-5，0表示未评分
+	Mood            int       `json:"mood,omitempty" gorm:"default:0"`          // 复盘：精力/心情1-5，0表示未填写
 
 	// 关联关系
-	Task Task `json:"task,omitempty" gorm:"foreignKey:TaskID"` // 关联的任务
-	User User `json:"user,omitempty" gorm:"foreignKey:UserID"` // 关联的用户
+	Task         *Task           `json:"task,omitempty" gorm:"foreignKey:TaskID"`                                         // 关联的任务
+	User         User            `json:"user,omitempty" gorm:"foreignKey:UserID"`                                         // 关联的用户
+	Pauses       []PomodoroPause `json:"pauses,omitempty" gorm:"foreignKey:PomodoroID;constraint:OnDelete:CASCADE"`       // 暂停片段
+	Distractions []Distraction   `json:"distractions,omitempty" gorm:"foreignKey:PomodoroID;constraint:OnDelete:CASCADE"` // 干扰记录
+}
+
+// 番茄钟类型
+const (
+	KindWork       = "工作"  // 工作时段
+	KindShortBreak = "短休息" // 短休息
+	KindLongBreak  = "长休息" // 长休息
+)
+
+// 中断原因代码
+const (
+	InterruptInternal = "internal_distraction" // 内部干扰（自己走神等）
+	InterruptExternal = "external_distraction" // 外部干扰（他人打断等）
+	InterruptMeeting  = "meeting"              // 会议
+	InterruptOther    = "other"                // 其他
+)
+
+// InterruptReasons 所有有效的中断原因代码
+var InterruptReasons = []string{InterruptInternal, InterruptExternal, InterruptMeeting, InterruptOther}
+
+// ValidInterruptReason 检查中断原因代码是否有效
+func ValidInterruptReason(reason string) bool {
+	for _, r := range InterruptReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidKind 检查番茄钟类型是否有效
+func ValidKind(kind string) bool {
+	switch kind {
+	case KindWork, KindShortBreak, KindLongBreak:
+		return true
+	}
+	return false
 }
 
 // TableName 指定表名
@@ -38,22 +95,118 @@ func (Pomodoro) TableName() string {
 	return "pomodoros"
 }
 
-// Duration 计算番茄钟实际持续时间（分钟）
+// Duration 计算番茄钟的净专注时间（分钟），扣除所有暂停片段
+// 需要预先加载Pauses
 func (p *Pomodoro) Duration() float64 {
 	if p.EndTime.IsZero() {
 		return 0
 	}
-	return p.EndTime.Sub(p.StartTime).Minutes()
+	return p.NetDuration(p.EndTime).Minutes()
+}
+
+// NetDuration 计算从开始到指定时间的净专注时长，扣除暂停片段
+func (p *Pomodoro) NetDuration(at time.Time) time.Duration {
+	if at.Before(p.StartTime) {
+		return 0
+	}
+	net := at.Sub(p.StartTime)
+	for i := range p.Pauses {
+		net -= p.Pauses[i].DurationBetween(p.StartTime, at)
+	}
+	if net < 0 {
+		return 0
+	}
+	return net
+}
+
+// OpenPause 返回尚未结束的暂停片段，不存在时返回nil
+func (p *Pomodoro) OpenPause() *PomodoroPause {
+	for i := range p.Pauses {
+		if p.Pauses[i].IsOpen() {
+			return &p.Pauses[i]
+		}
+	}
+	return nil
+}
+
+// Remaining 计算截至指定时间的剩余时长，暂停期间剩余时长保持不变
+func (p *Pomodoro) Remaining(at time.Time) time.Duration {
+	if open := p.OpenPause(); open != nil {
+		at = open.StartTime
+	}
+	if remaining := p.ExpectedEndTime.Sub(at); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// IsPaused 检查番茄钟是否处于暂停状态
+func (p *Pomodoro) IsPaused() bool {
+	return p.Status == StatusPaused
+}
+
+// NextSessionKind 根据上一个完成时段的类型推算下一个时段的类型
+// workSinceLongBreak为上次长休息之后完成的工作时段数量，interval为长休息间隔
+func NextSessionKind(lastKind string, workSinceLongBreak, interval int) string {
+	if lastKind != KindWork {
+		return KindWork
+	}
+	if interval > 0 && workSinceLongBreak >= interval {
+		return KindLongBreak
+	}
+	return KindShortBreak
+}
+
+// Minutes 将秒数换算为分钟，保留两位小数，所有接口返回的分钟数都使用该换算
+func Minutes(seconds int64) float64 {
+	return math.Round(float64(seconds)/60*100) / 100
+}
+
+// FindOverlapping 查找用户在[start, end)区间内与之重叠的番茄钟，不存在时返回nil
+// 活动番茄钟按预期结束时间计算，已过期的番茄钟不参与检查，excludeID不为0时排除该记录
+func FindOverlapping(db *gorm.DB, userID uint, start, end time.Time, excludeID uint) (*Pomodoro, error) {
+	// 时间以UTC保存，SQLite按字符串比较时间，绑定前同样转换为UTC
+	start, end = start.UTC(), end.UTC()
+	query := db.Where("user_id = ? AND status <> ? AND start_time < ?", userID, StatusExpired, end).
+		Where("(status IN ? AND expected_end_time > ?) OR (status NOT IN ? AND end_time > ?)",
+			ActiveStatuses, start, ActiveStatuses, start)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+
+	var pomodoro Pomodoro
+	err := query.Order("start_time").First(&pomodoro).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pomodoro, nil
+}
+
+// IsBreak 检查是否为休息时段
+func (p *Pomodoro) IsBreak() bool {
+	return p.Kind == KindShortBreak || p.Kind == KindLongBreak
 }
 
 // IsCompleted 检查番茄钟是否已完成
 func (p *Pomodoro) IsCompleted() bool {
-	return p.Status == "已完成"
+	return p.Status == StatusCompleted
+}
+
+// IsInterrupted 检查番茄钟是否已中断
+func (p *Pomodoro) IsInterrupted() bool {
+	return p.Status == StatusInterrupted
 }
 
 // IsOvertime 检查番茄钟是否超时
 func (p *Pomodoro) IsOvertime() bool {
 	if p.EndTime.IsZero() {
+		// 暂停期间计时冻结，以暂停开始时间判断
+		if open := p.OpenPause(); open != nil {
+			return open.StartTime.After(p.ExpectedEndTime)
+		}
 		return time.Now().After(p.ExpectedEndTime)
 	}
 	return p.EndTime.After(p.ExpectedEndTime)
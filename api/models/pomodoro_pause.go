@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PomodoroPause 番茄钟暂停片段模型
+// 与Python SQLAlchemy对比：
+// # class PomodoroPause(Base):
+// #     __tablename__ = "pomodoro_pauses"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     pomodoro_id = Column(Integer, ForeignKey("pomodoros.id"), index=True)
+// #     start_time = Column(DateTime, nullable=False)
+// #     end_time = Column(DateTime)  # 为空表示仍在暂停中
+type PomodoroPause struct {
+	gorm.Model
+	PomodoroID uint       `json:"pomodoroId" gorm:"not null;index"` // 关联的番茄钟ID
+	StartTime  time.Time  `json:"startTime" gorm:"not null"`        // 暂停开始时间
+	EndTime    *time.Time `json:"endTime"`                          // 恢复时间，为空表示仍在暂停中
+}
+
+// TableName 指定表名
+func (PomodoroPause) TableName() string {
+	return "pomodoro_pauses"
+}
+
+// IsOpen 检查暂停片段是否尚未结束
+func (p *PomodoroPause) IsOpen() bool {
+	return p.EndTime == nil
+}
+
+// DurationBetween 计算暂停片段落在[start, end]区间内的时长，未结束的片段按end计算
+func (p *PomodoroPause) DurationBetween(start, end time.Time) time.Duration {
+	from, to := p.StartTime, end
+	if p.EndTime != nil && p.EndTime.Before(end) {
+		to = *p.EndTime
+	}
+	if from.Before(start) {
+		from = start
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 修改记录的操作类型
+const (
+	RevisionUpdate = "update"
+	RevisionDelete = "delete"
+)
+
+// PomodoroRevision 番茄钟修改历史模型
+// 与Python SQLAlchemy对比：
+// # class PomodoroRevision(Base):
+// #     __tablename__ = "pomodoro_revisions"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     pomodoro_id = Column(Integer, index=True)  # 番茄钟删除后仍保留修改历史
+// #     user_id = Column(Integer, ForeignKey("users.id"))
+// #     action = Column(String, nullable=False)  # update, delete
+// #     before = Column(JSON)
+// #     after = Column(JSON)
+type PomodoroRevision struct {
+	gorm.Model
+	PomodoroID uint              `json:"pomodoroId" gorm:"not null;index"` // 关联的番茄钟ID（不设外键，删除后仍可查询）
+	UserID     uint              `json:"userId" gorm:"not null"`           // 关联的用户ID
+	Action     string            `json:"action" gorm:"not null"`           // 操作类型：update、delete
+	Before     *PomodoroSnapshot `json:"before" gorm:"type:text"`          // 修改前的数据
+	After      *PomodoroSnapshot `json:"after" gorm:"type:text"`           // 修改后的数据，删除时为空
+}
+
+// TableName 指定表名
+func (PomodoroRevision) TableName() string {
+	return "pomodoro_revisions"
+}
+
+// PomodoroSnapshot 番茄钟可修改字段的快照，以JSON格式存储
+type PomodoroSnapshot struct {
+	TaskID          *uint     `json:"taskId"`
+	Kind            string    `json:"kind"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	Status          string    `json:"status"`
+	InterruptReason string    `json:"interruptReason,omitempty"`
+	InterruptNote   string    `json:"interruptNote,omitempty"`
+	FocusedSeconds  int       `json:"focusedSeconds"`
+}
+
+// Snapshot 生成番茄钟当前数据的快照
+func (p *Pomodoro) Snapshot() *PomodoroSnapshot {
+	// 复制任务ID，避免更新记录时快照被一同修改
+	var taskID *uint
+	if p.TaskID != nil {
+		id := *p.TaskID
+		taskID = &id
+	}
+
+	return &PomodoroSnapshot{
+		TaskID:          taskID,
+		Kind:            p.Kind,
+		StartTime:       p.StartTime,
+		EndTime:         p.EndTime,
+		Status:          p.Status,
+		InterruptReason: p.InterruptReason,
+		InterruptNote:   p.InterruptNote,
+		FocusedSeconds:  p.FocusedSeconds,
+	}
+}
+
+// Value 实现driver.Valuer接口，写入数据库时序列化为JSON
+func (s *PomodoroSnapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现sql.Scanner接口，从数据库读取时反序列化JSON
+func (s *PomodoroSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	}
+	return errors.New("无法解析番茄钟快照")
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 番茄钟状态
+const (
+	StatusRunning     = "进行中"
+	StatusPaused      = "已暂停"
+	StatusCompleted   = "已完成"
+	StatusInterrupted = "已中断"
+	StatusExpired     = "已过期"
+)
+
+// ActiveStatuses 活动状态，每个用户同一时间最多只能有一个处于活动状态的番茄钟
+var ActiveStatuses = []string{StatusRunning, StatusPaused}
+
+// 状态转换错误代码
+const (
+	ErrCodeInvalidTransition = "invalid_transition"     // 当前状态不允许转换到目标状态
+	ErrCodeStaleStatus       = "stale_status"           // 状态已被并发请求修改
+	ErrCodeActiveExists      = "active_pomodoro_exists" // 已存在进行中的番茄钟
+	ErrCodeOverlap           = "overlapping_pomodoro"   // 与已有番茄钟的时间重叠
+	ErrCodeStillActive       = "pomodoro_active"        // 番茄钟仍在进行中，不能按历史记录修改
+	ErrCodeNotActive         = "pomodoro_not_active"    // 番茄钟已结束，不能再执行该操作
+)
+
+// pomodoroTransitions 允许的状态转换表，终止状态（已完成、已中断、已过期）不能再转换
+var pomodoroTransitions = map[string][]string{
+	StatusRunning: {StatusPaused, StatusCompleted, StatusInterrupted, StatusExpired},
+	StatusPaused:  {StatusRunning, StatusCompleted, StatusInterrupted, StatusExpired},
+}
+
+// pomodoroCorrections 修正历史记录时允许的状态变更
+// 只能在终止状态之间修正（例如把误点的中断改为完成），不能回到活动状态
+var pomodoroCorrections = map[string][]string{
+	StatusCompleted:   {StatusInterrupted},
+	StatusInterrupted: {StatusCompleted},
+	StatusExpired:     {StatusCompleted, StatusInterrupted},
+}
+
+// TransitionError 非法状态转换错误
+type TransitionError struct {
+	Code string `json:"code"` // 机器可读的错误代码
+	From string `json:"from"` // 当前状态
+	To   string `json:"to"`   // 目标状态
+}
+
+func (e *TransitionError) Error() string {
+	if e.Code == ErrCodeStaleStatus {
+		return fmt.Sprintf("番茄钟状态已被修改，无法从「%s」转换为「%s」", e.From, e.To)
+	}
+	return fmt.Sprintf("番茄钟无法从「%s」转换为「%s」", e.From, e.To)
+}
+
+// ValidStatus 检查状态是否有效
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusRunning, StatusPaused, StatusCompleted, StatusInterrupted, StatusExpired:
+		return true
+	}
+	return false
+}
+
+// IsActiveStatus 检查状态是否为活动状态（进行中或已暂停）
+func IsActiveStatus(status string) bool {
+	return status == StatusRunning || status == StatusPaused
+}
+
+// IsTerminalStatus 检查状态是否为终止状态
+func IsTerminalStatus(status string) bool {
+	return ValidStatus(status) && len(pomodoroTransitions[status]) == 0
+}
+
+// CanTransition 检查是否允许从from状态转换到to状态
+func CanTransition(from, to string) bool {
+	for _, next := range pomodoroTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCorrection 检查修正历史记录时的状态变更，不允许时返回*TransitionError
+func CheckCorrection(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, next := range pomodoroCorrections[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &TransitionError{Code: ErrCodeInvalidTransition, From: from, To: to}
+}
+
+// CheckTransition 检查状态转换，不允许时返回*TransitionError
+func CheckTransition(from, to string) error {
+	if !CanTransition(from, to) {
+		return &TransitionError{Code: ErrCodeInvalidTransition, From: from, To: to}
+	}
+	return nil
+}
+
+// Transition 将番茄钟转换到目标状态，updates为需要同时更新的其他字段
+// 更新条件包含当前状态，若状态已被并发请求修改则返回*TransitionError
+func (p *Pomodoro) Transition(tx *gorm.DB, to string, updates map[string]interface{}) error {
+	from := p.Status
+	if err := CheckTransition(from, to); err != nil {
+		return err
+	}
+
+	updates["status"] = to
+	result := tx.Model(p).Where("status = ?", from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &TransitionError{Code: ErrCodeStaleStatus, From: from, To: to}
+	}
+	return nil
+}
+
+// Finish 结束番茄钟：关闭未结束的暂停片段，写入结束时间和净专注时长，并更新当天的每日汇总
+// 需要预先加载Pauses，结束时间以UTC保存
+func (p *Pomodoro) Finish(tx *gorm.DB, to string, end time.Time, updates map[string]interface{}) error {
+	end = end.UTC()
+	if pause := p.OpenPause(); pause != nil {
+		if err := tx.Model(pause).Update("end_time", end).Error; err != nil {
+			return err
+		}
+	}
+
+	updates["end_time"] = end
+	updates["focused_seconds"] = int(p.NetDuration(end).Seconds())
+	if err := p.Transition(tx, to, updates); err != nil {
+		return err
+	}
+	return RefreshDailyRollups(tx, p.UserID, p.StartTime)
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DefaultProjectColor 未指定颜色时项目使用的颜色
+const DefaultProjectColor = "#f2543d"
+
+// MaxProjectNameLength 项目名称的最大长度（字符数）
+const MaxProjectNameLength = 50
+
+// projectColorPattern 项目颜色格式：#RRGGBB
+var projectColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Project 项目（任务清单）模型，用于对任务分组
+// 与Python SQLAlchemy对比：
+// # class Project(Base):
+// #     __tablename__ = "projects"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     user_id = Column(Integer, ForeignKey("users.id"), index=True)
+// #     name = Column(String(50), nullable=False)
+// #     color = Column(String(7), default="#f2543d")
+// #     archived = Column(Boolean, default=False)
+// #     position = Column(Integer, default=0)  # 排列顺序，越小越靠前
+type Project struct {
+	gorm.Model
+	UserID   uint   `json:"userId" gorm:"not null;index"`                            // 关联的用户ID
+	Name     string `json:"name" gorm:"type:varchar(50);not null"`                   // 项目名称
+	Color    string `json:"color" gorm:"type:varchar(7);not null;default:'#f2543d'"` // 颜色，格式#RRGGBB
+	Archived bool   `json:"archived" gorm:"not null;default:false"`                  // 是否已归档
+	Position int    `json:"position" gorm:"not null;default:0"`                      // 排列顺序，越小越靠前
+
+	// 任务数量，不持久化，由接口查询时填充
+	TaskCount          int64 `json:"taskCount" gorm:"-"`          // 任务总数
+	CompletedTaskCount int64 `json:"completedTaskCount" gorm:"-"` // 已完成的任务数量
+}
+
+// TableName 指定表名
+func (Project) TableName() string {
+	return "projects"
+}
+
+// NormalizeProjectName 去除名称首尾空白并验证长度
+func NormalizeProjectName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("项目名称不能为空")
+	}
+	if len([]rune(name)) > MaxProjectNameLength {
+		return "", errors.New("项目名称不能超过50个字符")
+	}
+	return name, nil
+}
+
+// ValidateProjectColor 验证项目颜色格式
+func ValidateProjectColor(color string) error {
+	if !projectColorPattern.MatchString(color) {
+		return errors.New("无效的项目颜色，格式应为#RRGGBB")
+	}
+	return nil
+}
+
+// NextProjectPosition 返回用户新建项目的排列位置（排在最后）
+func NextProjectPosition(db *gorm.DB, userID uint) (int, error) {
+	var position int
+	err := db.Model(&Project{}).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Where("user_id = ?", userID).
+		Scan(&position).Error
+	return position, err
+}
+
+// FillTaskCounts 用一次聚合查询填充项目的任务数量
+func FillTaskCounts(db *gorm.DB, projects []Project) error {
+	if len(projects) == 0 {
+		return nil
+	}
+	projectIDs := make([]uint, len(projects))
+	for i := range projects {
+		projectIDs[i] = projects[i].ID
+	}
+
+	var rows []struct {
+		ProjectID      uint
+		TaskCount      int64
+		CompletedCount int64
+	}
+	err := db.Model(&Task{}).
+		Select("project_id, COUNT(*) AS task_count, "+
+			"COALESCE(SUM(CASE WHEN completed = ? THEN 1 ELSE 0 END), 0) AS completed_count", true).
+		Where("project_id IN ?", projectIDs).
+		Group("project_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for i, row := range rows {
+		counts[row.ProjectID] = i
+	}
+	for i := range projects {
+		if j, ok := counts[projects[i].ID]; ok {
+			projects[i].TaskCount = rows[j].TaskCount
+			projects[i].CompletedTaskCount = rows[j].CompletedCount
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 分享令牌的用途，每种用途只能访问对应的只读接口
+const (
+	ShareScopeCalendar = "calendar" // 专注日历SVG图片
+)
+
+// MaxShareTokenNameLength 分享令牌名称的最大长度（字符数）
+const MaxShareTokenNameLength = 50
+
+// ShareToken 只读分享令牌，用于嵌入到团队wiki等场景的图片链接，可随时撤销
+// 只保存令牌的SHA-256摘要，令牌明文仅在创建时返回一次
+// 与Python SQLAlchemy对比：
+// # class ShareToken(Base):
+// #     __tablename__ = "share_tokens"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     user_id = Column(Integer, ForeignKey("users.id"), index=True)
+// #     scope = Column(String(20), nullable=False)
+// #     name = Column(String(50))
+// #     token_hash = Column(String(64), unique=True, nullable=False)
+// #     last_used_at = Column(DateTime)
+type ShareToken struct {
+	gorm.Model
+	UserID     uint       `json:"userId" gorm:"not null;index"`                     // 关联的用户ID
+	Scope      string     `json:"scope" gorm:"type:varchar(20);not null"`           // 用途
+	Name       string     `json:"name" gorm:"type:varchar(50);not null;default:''"` // 备注名称，例如嵌入的页面
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`   // 令牌的SHA-256摘要（十六进制）
+	LastUsedAt *time.Time `json:"lastUsedAt"`                                       // 最后使用时间
+}
+
+// TableName 指定表名
+func (ShareToken) TableName() string {
+	return "share_tokens"
+}
+
+// ValidShareScope 检查分享令牌用途是否有效
+func ValidShareScope(scope string) bool {
+	return scope == ShareScopeCalendar
+}
+
+// NewShareToken 生成随机的令牌明文，返回明文和尚未保存的分享令牌记录
+func NewShareToken(userID uint, scope, name string) (string, ShareToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", ShareToken{}, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, ShareToken{UserID: userID, Scope: scope, Name: name, TokenHash: HashShareToken(raw)}, nil
+}
+
+// HashShareToken 计算令牌明文的摘要
+func HashShareToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindShareToken 查找指定用途的有效分享令牌，已撤销（删除）的令牌查不到
+func FindShareToken(db *gorm.DB, raw, scope string) (*ShareToken, error) {
+	var token ShareToken
+	err := db.Where("token_hash = ? AND scope = ?", HashShareToken(raw), scope).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -18,6 +18,9 @@ import (
 // #     completed = Column(Boolean, default=False)
 // #     due_date = Column(DateTime)
 // #     user_id = Column(Integer, ForeignKey("users.id"))
+// #     estimated_pomodoros = Column(Integer, default=0)  # 预估番茄钟数量，0表示未估算
+// #     parent_id = Column(Integer, ForeignKey("tasks.id"), index=True)  # 父任务ID，为空表示顶层任务
+// #     project_id = Column(Integer, ForeignKey("projects.id"), index=True)  # 所属项目ID，为空表示未分组
 // #     created_at = Column(DateTime, default=datetime.utcnow)
 type Task struct {
 	gorm.Model
@@ -28,6 +31,18 @@ type Task struct {
 	DueDate     time.Time `json:"dueDate"`                        // 截止日期
 	UserID      uint      `json:"userId" gorm:"not null"`         // 关联的用户ID
 
+	EstimatedPomodoros int   `json:"estimatedPomodoros" gorm:"not null;default:0"` // 预估番茄钟数量，0表示未估算
+	ParentID           *uint `json:"parentId" gorm:"index"`                        // 父任务ID，为空表示顶层任务，可任意层级嵌套
+	ProjectID          *uint `json:"projectId" gorm:"index"`                       // 所属项目ID，为空表示未分组
+
+	// 番茄钟进度，不持久化，由接口查询时填充
+	ActualPomodoros    int64              `json:"actualPomodoros" gorm:"-"`         // 实际完成的番茄钟数量
+	RemainingPomodoros *int64             `json:"remainingPomodoros" gorm:"-"`      // 剩余预估番茄钟数量，未估算时为null
+	PomodoroStats      *TaskPomodoroStats `json:"pomodoroStats,omitempty" gorm:"-"` // 番茄钟汇总，请求include=pomodoros时返回
+	Forecast           *TaskForecast      `json:"forecast,omitempty" gorm:"-"`      // 完成预测，请求include=forecast时返回
+	Children           []Task             `json:"children,omitempty" gorm:"-"`      // 子任务，查询子树时返回
+	Progress           *TaskProgress      `json:"progress,omitempty" gorm:"-"`      // 自身及后代的汇总进度，查询子树时返回
+
 	// 关联关系
 	User      User       `json:"user,omitempty" gorm:"foreignKey:UserID"`                                  // 关联的用户
 	Pomodoros []Pomodoro `json:"pomodoros,omitempty" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"` // 关联的番茄钟记录
@@ -46,6 +61,36 @@ func (t *Task) IsOverdue() bool {
 	return !t.Completed && time.Now().After(t.DueDate)
 }
 
+// IsAtRisk 检查任务是否有逾期风险：已过期，或预计完成的日期（用户时区）晚于截止日期
+// finish为零值表示无法预测，此时只判断是否已过期
+func (t *Task) IsAtRisk(finish time.Time, loc *time.Location) bool {
+	if t.DueDate.IsZero() || t.Completed {
+		return false
+	}
+	if t.IsOverdue() {
+		return true
+	}
+	if finish.IsZero() {
+		return false
+	}
+	return finish.In(loc).Format("2006-01-02") > t.DueDate.In(loc).Format("2006-01-02")
+}
+
+// 无法预测完成日期的原因
+const (
+	ForecastNotEstimated = "not_estimated" // 任务未估算番茄钟数量
+	ForecastNoVelocity   = "no_velocity"   // 历史上没有完成的番茄钟
+)
+
+// TaskForecast 任务的完成预测
+type TaskForecast struct {
+	Position int     `json:"position"`         // 在待办队列中的位置，从1开始
+	Date     *string `json:"date"`             // 预计完成日期（用户时区），无法预测时为null
+	AtRisk   bool    `json:"atRisk"`           // 是否有逾期风险
+	Overdue  bool    `json:"overdue"`          // 是否已过期
+	Reason   string  `json:"reason,omitempty"` // 无法预测的原因
+}
+
 // PomodoroCount 获取任务的番茄钟数量
 func (t *Task) PomodoroCount(db *gorm.DB) int64 {
 	var count int64
@@ -53,9 +98,67 @@ func (t *Task) PomodoroCount(db *gorm.DB) int64 {
 	return count
 }
 
-// CompletedPomodoroCount 获取任务完成的番茄钟数量
+// CompletedPomodoroCount 获取任务完成的番茄钟（工作时段）数量
 func (t *Task) CompletedPomodoroCount(db *gorm.DB) int64 {
 	var count int64
-	db.Model(&Pomodoro{}).Where("task_id = ? AND status = ?", t.ID, "已完成").Count(&count)
+	db.Model(&Pomodoro{}).Where("task_id = ? AND kind = ? AND status = ?", t.ID, KindWork, StatusCompleted).Count(&count)
 	return count
 }
+
+// TaskPomodoroStats 任务的番茄钟汇总
+type TaskPomodoroStats struct {
+	PomodoroCount  int64   `json:"pomodoroCount"`  // 番茄钟总数
+	CompletedCount int64   `json:"completedCount"` // 已完成的番茄钟数量
+	FocusedMinutes float64 `json:"focusedMinutes"` // 已完成番茄钟的净专注时长（分钟）
+	FocusedSeconds int64   `json:"-"`              // 已完成番茄钟的净专注秒数，用于汇总后再换算
+}
+
+// PomodoroStatsByTask 用一次聚合查询统计多个任务的番茄钟数量和专注时长
+// 只统计工作时段，关联到任务的休息时段不计入；没有番茄钟记录的任务不会出现在结果中
+func PomodoroStatsByTask(db *gorm.DB, taskIDs []uint) (map[uint]TaskPomodoroStats, error) {
+	stats := make(map[uint]TaskPomodoroStats, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return stats, nil
+	}
+
+	var rows []struct {
+		TaskID         uint
+		PomodoroCount  int64
+		CompletedCount int64
+		FocusedSeconds int64
+	}
+	err := db.Model(&Pomodoro{}).
+		Select("task_id, COUNT(*) AS pomodoro_count, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS completed_count, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN focused_seconds ELSE 0 END), 0) AS focused_seconds",
+			StatusCompleted, StatusCompleted).
+		Where("task_id IN ? AND kind = ?", taskIDs, KindWork).
+		Group("task_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		stats[row.TaskID] = TaskPomodoroStats{
+			PomodoroCount:  row.PomodoroCount,
+			CompletedCount: row.CompletedCount,
+			FocusedMinutes: Minutes(row.FocusedSeconds),
+			FocusedSeconds: row.FocusedSeconds,
+		}
+	}
+	return stats, nil
+}
+
+// SetPomodoroProgress 根据实际完成的番茄钟数量填充进度字段
+func (t *Task) SetPomodoroProgress(actual int64) {
+	t.ActualPomodoros = actual
+	t.RemainingPomodoros = nil
+	if t.EstimatedPomodoros > 0 {
+		remaining := int64(t.EstimatedPomodoros) - actual
+		if remaining < 0 {
+			remaining = 0
+		}
+		t.RemainingPomodoros = &remaining
+	}
+}
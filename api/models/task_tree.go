@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 删除有子任务的任务时的处理方式
+const (
+	DeleteCascade = "cascade" // 同时删除所有后代任务
+	DeletePromote = "promote" // 子任务提升到被删除任务的父任务下
+)
+
+// ErrTaskCycle 移动任务会使其成为自身或后代的子任务
+var ErrTaskCycle = errors.New("不能将任务移动到自身或其子任务下")
+
+// TaskProgress 任务及其所有后代的汇总进度
+type TaskProgress struct {
+	Subtasks           int     `json:"subtasks"`           // 后代任务数量
+	CompletedSubtasks  int     `json:"completedSubtasks"`  // 已完成的后代任务数量
+	EstimatedPomodoros int64   `json:"estimatedPomodoros"` // 自身及后代的预估番茄钟数量之和
+	CompletedPomodoros int64   `json:"completedPomodoros"` // 自身及后代已完成的番茄钟数量
+	FocusedMinutes     float64 `json:"focusedMinutes"`     // 自身及后代已完成番茄钟的净专注时长（分钟）
+
+	focusedSeconds int64 // 自身及后代的净专注秒数，按秒累加后再换算为分钟，避免舍入误差累积
+}
+
+// subtreeQuery 递归查询以rootID为根的子树（包含根）中所有任务的ID
+// PostgreSQL和SQLite都支持WITH RECURSIVE；使用UNION去重，即使数据中存在环也能结束
+const subtreeQuery = `WITH RECURSIVE subtree(id) AS (
+	SELECT id FROM tasks WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	UNION
+	SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+	WHERE tasks.user_id = ? AND tasks.deleted_at IS NULL
+)
+SELECT id FROM subtree`
+
+// SubtreeIDs 查询以rootID为根的子树中所有任务的ID（包含根），根任务不存在时返回空
+func SubtreeIDs(db *gorm.DB, userID, rootID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(subtreeQuery, rootID, userID, userID).Scan(&ids).Error
+	return ids, err
+}
+
+// LoadSubtree 加载以rootID为根的子树，填充每个任务的子任务、番茄钟进度和汇总进度
+func LoadSubtree(db *gorm.DB, userID, rootID uint) (*Task, error) {
+	ids, err := SubtreeIDs(db, userID, rootID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var tasks []Task
+	if err := db.Where("id IN ?", ids).Order("created_at").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	stats, err := PomodoroStatsByTask(db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*Task, len(tasks))
+	for i := range tasks {
+		tasks[i].SetPomodoroProgress(stats[tasks[i].ID].CompletedCount)
+		byID[tasks[i].ID] = &tasks[i]
+	}
+	children := make(map[uint][]uint, len(tasks))
+	for _, task := range tasks {
+		if task.ID != rootID && task.ParentID != nil {
+			children[*task.ParentID] = append(children[*task.ParentID], task.ID)
+		}
+	}
+
+	// 自底向上汇总进度，再组装子任务
+	var build func(id uint) Task
+	build = func(id uint) Task {
+		task := *byID[id]
+		taskStats := stats[id]
+		progress := &TaskProgress{
+			EstimatedPomodoros: int64(task.EstimatedPomodoros),
+			CompletedPomodoros: taskStats.CompletedCount,
+			focusedSeconds:     taskStats.FocusedSeconds,
+		}
+		task.Children = []Task{}
+		for _, childID := range children[id] {
+			child := build(childID)
+			progress.Subtasks += child.Progress.Subtasks + 1
+			progress.CompletedSubtasks += child.Progress.CompletedSubtasks
+			if child.Completed {
+				progress.CompletedSubtasks++
+			}
+			progress.EstimatedPomodoros += child.Progress.EstimatedPomodoros
+			progress.CompletedPomodoros += child.Progress.CompletedPomodoros
+			progress.focusedSeconds += child.Progress.focusedSeconds
+			task.Children = append(task.Children, child)
+		}
+		progress.FocusedMinutes = Minutes(progress.focusedSeconds)
+		task.Progress = progress
+		return task
+	}
+
+	root := build(rootID)
+	return &root, nil
+}
+
+// MoveTask 将任务及其子树移动到新的父任务下，parentID为nil时移动到顶层
+// 新的父任务必须属于同一用户，且不能是任务自身或其后代
+// 先锁定用户行，同一用户的移动依次执行，避免两个并发移动各自通过环检查后形成环
+func MoveTask(tx *gorm.DB, task *Task, parentID *uint) error {
+	if err := LockUser(tx, task.UserID); err != nil {
+		return err
+	}
+	if parentID != nil {
+		ids, err := SubtreeIDs(tx, task.UserID, task.ID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if id == *parentID {
+				return ErrTaskCycle
+			}
+		}
+
+		var parent Task
+		if err := tx.Select("id").Where("id = ? AND user_id = ?", *parentID, task.UserID).First(&parent).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(task).Update("parent_id", parentID).Error
+}
+
+// DeleteTaskTree 删除任务：cascade删除整个子树，promote将直接子任务提升到被删除任务的父任务下
+func DeleteTaskTree(tx *gorm.DB, task *Task, mode string) error {
+	if mode == DeleteCascade {
+		ids, err := SubtreeIDs(tx, task.UserID, task.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&Task{}).Error
+	}
+
+	err := tx.Model(&Task{}).
+		Where("parent_id = ? AND user_id = ?", task.ID, task.UserID).
+		Update("parent_id", task.ParentID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(task).Error
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 番茄钟时长默认值（分钟）
+const (
+	DefaultWorkMinutes       = 25 // 标准番茄钟25分钟
+	DefaultShortBreakMinutes = 5  // 短休息5分钟
+	DefaultLongBreakMinutes  = 15 // 长休息15分钟
+	DefaultLongBreakInterval = 4  // 每4个番茄钟后进行一次长休息
+)
+
+// 时长取值范围（分钟）
+const (
+	MinSessionMinutes = 1
+	MaxSessionMinutes = 180
+)
+
+// TimerSettings 用户计时器设置模型
+// 与Python SQLAlchemy对比：
+// # class TimerSettings(Base):
+// #     __tablename__ = "timer_settings"
+// #     id = Column(Integer, primary_key=True, index=True)
+// #     user_id = Column(Integer, ForeignKey("users.id"), unique=True)
+// #     work_minutes = Column(Integer, default=25)
+// #     short_break_minutes = Column(Integer, default=5)
+// #     long_break_minutes = Column(Integer, default=15)
+// #     long_break_interval = Column(Integer, default=4)
+// #     overdue_policy = Column(String, default="expire")  # expire, complete
+type TimerSettings struct {
+	gorm.Model
+	UserID            uint   `json:"userId" gorm:"uniqueIndex;not null"`             // 关联的用户ID，每个用户一条设置
+	WorkMinutes       int    `json:"workMinutes" gorm:"not null;default:25"`         // 番茄钟时长
+	ShortBreakMinutes int    `json:"shortBreakMinutes" gorm:"not null;default:5"`    // 短休息时长
+	LongBreakMinutes  int    `json:"longBreakMinutes" gorm:"not null;default:15"`    // 长休息时长
+	LongBreakInterval int    `json:"longBreakInterval" gorm:"not null;default:4"`    // 长休息间隔（番茄钟个数）
+	OverduePolicy     string `json:"overduePolicy" gorm:"not null;default:'expire'"` // 超时未结束的番茄钟的处理策略
+}
+
+// 超时处理策略：番茄钟超过预期结束时间（加宽限期）仍未结束时如何处理
+const (
+	OverduePolicyExpire   = "expire"   // 标记为已过期，不计入统计
+	OverduePolicyComplete = "complete" // 按预期结束时间自动完成
+)
+
+// TableName 指定表名
+func (TimerSettings) TableName() string {
+	return "timer_settings"
+}
+
+// DefaultTimerSettings 返回用户的默认计时器设置（未保存到数据库）
+func DefaultTimerSettings(userID uint) TimerSettings {
+	return TimerSettings{
+		UserID:            userID,
+		WorkMinutes:       DefaultWorkMinutes,
+		ShortBreakMinutes: DefaultShortBreakMinutes,
+		LongBreakMinutes:  DefaultLongBreakMinutes,
+		LongBreakInterval: DefaultLongBreakInterval,
+		OverduePolicy:     OverduePolicyExpire,
+	}
+}
+
+// Validate 校验计时器设置
+func (s *TimerSettings) Validate() error {
+	if !ValidSessionMinutes(s.WorkMinutes) {
+		return errors.New("番茄钟时长必须在1到180分钟之间")
+	}
+	if !ValidSessionMinutes(s.ShortBreakMinutes) {
+		return errors.New("短休息时长必须在1到180分钟之间")
+	}
+	if !ValidSessionMinutes(s.LongBreakMinutes) {
+		return errors.New("长休息时长必须在1到180分钟之间")
+	}
+	if s.LongBreakMinutes < s.ShortBreakMinutes {
+		return errors.New("长休息时长不能短于短休息时长")
+	}
+	if s.LongBreakInterval < 1 || s.LongBreakInterval > 12 {
+		return errors.New("长休息间隔必须在1到12之间")
+	}
+	if s.OverduePolicy != OverduePolicyExpire && s.OverduePolicy != OverduePolicyComplete {
+		return errors.New("超时处理策略必须为expire或complete")
+	}
+	return nil
+}
+
+// MinutesFor 返回指定类型时段的时长（分钟）
+func (s *TimerSettings) MinutesFor(kind string) int {
+	switch kind {
+	case KindShortBreak:
+		return s.ShortBreakMinutes
+	case KindLongBreak:
+		return s.LongBreakMinutes
+	default:
+		return s.WorkMinutes
+	}
+}
+
+// ValidSessionMinutes 检查时长是否在允许范围内
+func ValidSessionMinutes(minutes int) bool {
+	return minutes >= MinSessionMinutes && minutes <= MaxSessionMinutes
+}
+
+// GetTimerSettings 获取用户的计时器设置，不存在时返回默认值
+func GetTimerSettings(db *gorm.DB, userID uint) (TimerSettings, error) {
+	var settings TimerSettings
+	err := db.Where("user_id = ?", userID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultTimerSettings(userID), nil
+	}
+	return settings, err
+}
@@ -2,9 +2,11 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // User 用户模型
@@ -17,19 +19,62 @@ import (
 // #     name = Column(String, nullable=False)
 // #     created_at = Column(DateTime, default=datetime.utcnow)
 // #     last_login = Column(DateTime)
+// #     timezone = Column(String, default="UTC")  # IANA时区名称
 type User struct {
 	gorm.Model           // 内嵌gorm.Model，包含ID、CreatedAt、UpdatedAt、DeletedAt字段
-	Email      string    `json:"email" gorm:"uniqueIndex;not null"` // 邮箱，唯一索引
-	Password   string    `json:"-" gorm:"not null"`                 // 密码，不序列化到JSON
-	Name       string    `json:"name" gorm:"not null"`              // 用户名
-	LastLogin  time.Time `json:"lastLogin"`                         // 最后登录时间
+	Email      string    `json:"email" gorm:"uniqueIndex;not null"`      // 邮箱，唯一索引
+	Password   string    `json:"-" gorm:"not null"`                      // 密码，不序列化到JSON
+	Name       string    `json:"name" gorm:"not null"`                   // 用户名
+	LastLogin  time.Time `json:"lastLogin"`                              // 最后登录时间
+	Timezone   string    `json:"timezone" gorm:"not null;default:'UTC'"` // IANA时区名称，统计按该时区的日期分组
 
 	// 关联关系
-	Tasks     []Task     `json:"tasks,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`     // 用户的任务
-	Pomodoros []Pomodoro `json:"pomodoros,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // 用户的番茄钟记录
+	Tasks         []Task         `json:"tasks,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`         // 用户的任务
+	Pomodoros     []Pomodoro     `json:"pomodoros,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`     // 用户的番茄钟记录
+	TimerSettings *TimerSettings `json:"timerSettings,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // 用户的计时器设置
 }
 
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
 }
+
+// DefaultTimezone 未设置时区时使用的默认时区
+const DefaultTimezone = "UTC"
+
+// LoadTimezone 解析IANA时区名称（如Asia/Shanghai），空字符串视为默认时区
+// 不接受Local，避免统计结果依赖服务器所在时区
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("无效的时区: %s", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", name)
+	}
+	return loc, nil
+}
+
+// Location 返回用户的时区
+func (u *User) Location() (*time.Location, error) {
+	return LoadTimezone(u.Timezone)
+}
+
+// GetUserLocation 查询用户的时区
+func GetUserLocation(db *gorm.DB, userID uint) (*time.Location, error) {
+	var user User
+	if err := db.Select("id", "timezone").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return user.Location()
+}
+
+// LockUser 在事务中锁定用户行（SELECT ... FOR UPDATE），串行化同一用户的并发修改
+// SQLite不支持行锁（同一时间只有一个写事务），GORM会忽略锁定子句
+func LockUser(tx *gorm.DB, userID uint) error {
+	var user User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error
+}
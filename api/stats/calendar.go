@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// CalendarDaysCount 年度日历包含的天数
+const CalendarDaysCount = 365
+
+// CalendarDay 年度日历中的一天
+type CalendarDay struct {
+	Date    string  `json:"date"`    // 用户时区的日期
+	Count   int     `json:"count"`   // 已完成的工作时段数量
+	Minutes float64 `json:"minutes"` // 净专注时长（分钟）
+	Level   int     `json:"level"`   // 强度等级0-4，0表示没有专注记录
+
+	seconds int64
+}
+
+// Calendar 年度专注日历
+type Calendar struct {
+	From       string        `json:"from"`
+	To         string        `json:"to"`
+	Timezone   string        `json:"timezone"`
+	WeekStart  string        `json:"weekStart"`
+	Thresholds []float64     `json:"thresholds"` // 等级1-3的专注时长上限（分钟），超过最后一个为等级4
+	TotalCount int           `json:"totalCount"`
+	TotalDays  int           `json:"activeDays"` // 有专注记录的天数
+	Days       []CalendarDay `json:"days"`
+
+	weekStart time.Weekday
+	from      time.Time
+}
+
+// Calendar 计算截至今天（含）最近365天的专注日历
+// 每天的强度等级按有专注记录的日期的专注时长四分位数划分
+func (s *Service) Calendar(userID uint, loc *time.Location, weekStart time.Weekday, now time.Time) (*Calendar, error) {
+	r := LastDays(now, loc, CalendarDaysCount)
+
+	totals, err := s.DailyTotals(userID, loc, r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{
+		From:      r.FromDate(),
+		To:        r.ToDate(),
+		Timezone:  loc.String(),
+		WeekStart: weekStartName(weekStart),
+		Days:      make([]CalendarDay, 0, r.Days),
+		weekStart: weekStart,
+		from:      r.From,
+	}
+
+	for day := r.From; day.Before(r.To); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		total := totals[date]
+		calendar.Days = append(calendar.Days, CalendarDay{Date: date, Count: int(total.Count), seconds: total.FocusedSeconds})
+	}
+
+	// 按有记录日期的专注时长计算四分位数
+	var active []int64
+	for i := range calendar.Days {
+		day := &calendar.Days[i]
+		day.Minutes = Minutes(day.seconds)
+		calendar.TotalCount += day.Count
+		if day.Count > 0 {
+			active = append(active, day.seconds)
+		}
+	}
+	calendar.TotalDays = len(active)
+
+	thresholds := quartiles(active)
+	calendar.Thresholds = make([]float64, len(thresholds))
+	for i, threshold := range thresholds {
+		calendar.Thresholds[i] = Minutes(threshold)
+	}
+	for i := range calendar.Days {
+		calendar.Days[i].Level = level(calendar.Days[i], thresholds)
+	}
+
+	return calendar, nil
+}
+
+// quartiles 返回25%、50%、75%分位数，没有数据时返回空
+func quartiles(values []int64) []int64 {
+	if len(values) == 0 {
+		return []int64{}
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	result := make([]int64, 3)
+	for i := range result {
+		result[i] = sorted[(len(sorted)-1)*(i+1)/4]
+	}
+	return result
+}
+
+// level 根据四分位数计算强度等级
+func level(day CalendarDay, thresholds []int64) int {
+	if day.Count == 0 {
+		return 0
+	}
+	for i, threshold := range thresholds {
+		if day.seconds <= threshold {
+			return i + 1
+		}
+	}
+	return 4
+}
+
+// weekStartName 返回星期的小写英文名称
+func weekStartName(day time.Weekday) string {
+	return strings.ToLower(day.String())
+}
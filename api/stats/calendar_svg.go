@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// SVG日历的布局参数（像素）
+const (
+	svgCellSize   = 11
+	svgCellGap    = 2
+	svgLeftMargin = 30 // 星期标签宽度
+	svgTopMargin  = 20 // 月份标签高度
+)
+
+// svgLevelColors 强度等级0-4对应的颜色
+var svgLevelColors = [5]string{"#ebedf0", "#ffc9c0", "#ff8a75", "#f2543d", "#c4301c"}
+
+// RenderSVG 将日历渲染为SVG图片，每列为一周，每行为星期几
+func (cal *Calendar) RenderSVG() []byte {
+	pitch := svgCellSize + svgCellGap
+
+	// 网格从范围第一天所在周的起始日开始
+	offset := (int(cal.from.Weekday()) - int(cal.weekStart) + 7) % 7
+	weeks := (offset + len(cal.Days) + 6) / 7
+	width := svgLeftMargin + weeks*pitch
+	height := svgTopMargin + 7*pitch
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="9" fill="#767676">`,
+		width, height, width, height)
+	buf.WriteString("\n")
+
+	// 星期标签，只标注第2、4、6行
+	for _, row := range []int{1, 3, 5} {
+		name := time.Weekday((int(cal.weekStart) + row) % 7).String()[:3]
+		fmt.Fprintf(&buf, `<text x="0" y="%d">%s</text>`+"\n", svgTopMargin+row*pitch+svgCellSize-2, name)
+	}
+
+	lastMonth := time.Month(0)
+	for i, day := range cal.Days {
+		date := cal.from.AddDate(0, 0, i)
+		column := (offset + i) / 7
+		row := (offset + i) % 7
+		x := svgLeftMargin + column*pitch
+		y := svgTopMargin + row*pitch
+
+		// 月份标签标注在每月1日所在的列上，范围开头不足一周的月份不标注
+		if date.Month() != lastMonth && date.Day() <= 7 {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d">%s</text>`+"\n", x, svgTopMargin-6, date.Month().String()[:3])
+			lastMonth = date.Month()
+		}
+
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s"><title>%s: %d个番茄钟，%.0f分钟</title></rect>`+"\n",
+			x, y, svgCellSize, svgCellSize, svgLevelColors[day.Level], day.Date, day.Count, day.Minutes)
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"TomatoList/models"
+)
+
+// 计算完成速度使用的历史天数
+const (
+	DefaultVelocityDays = 28
+	MaxVelocityDays     = 365
+)
+
+// ErrInvalidVelocityDays 历史天数超出范围
+var ErrInvalidVelocityDays = errors.New("历史天数必须在1到365之间")
+
+// priorityRank 待办队列中优先级的排序
+var priorityRank = map[string]int{"高": 0, "中": 1, "低": 2}
+
+// Velocity 历史完成速度
+type Velocity struct {
+	Days           int     `json:"days"`
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	CompletedCount int64   `json:"completedCount"`
+	PerDay         float64 `json:"perDay"` // 平均每天完成的工作时段数量
+}
+
+// Forecast 未完成任务的完成预测
+type Forecast struct {
+	Velocity    Velocity      `json:"velocity"`
+	Today       string        `json:"today"`
+	TodayCount  int64         `json:"todayCount"` // 今天已完成的工作时段数量
+	Timezone    string        `json:"timezone"`
+	AtRiskCount int           `json:"atRiskCount"`
+	Tasks       []models.Task `json:"tasks"` // 按待办队列顺序排列
+}
+
+// Forecast 根据最近days天（不含今天）平均每天完成的工作时段数量，预测每个未完成任务的完成日期
+// 假设按截止日期、优先级、创建顺序依次完成任务，每个任务的完成日期取决于排在它之前的剩余预估数量之和；
+// 今天按平均速度扣除已完成的数量后还有剩余的部分。未估算的任务不占用队列，也无法预测
+func (s *Service) Forecast(userID uint, loc *time.Location, now time.Time, days int) (*Forecast, error) {
+	if days < 1 || days > MaxVelocityDays {
+		return nil, ErrInvalidVelocityDays
+	}
+
+	today := StartOfDay(now, loc)
+	history := NewRange(today.AddDate(0, 0, -days), today)
+	totals, err := s.DailyTotals(userID, loc, NewRange(history.From, today.AddDate(0, 0, 1)))
+	if err != nil {
+		return nil, err
+	}
+
+	todayDate := today.Format("2006-01-02")
+	forecast := &Forecast{
+		Velocity: Velocity{
+			Days: days,
+			From: history.FromDate(),
+			To:   history.ToDate(),
+		},
+		Today:      todayDate,
+		TodayCount: totals[todayDate].Count,
+		Timezone:   loc.String(),
+	}
+	for date, total := range totals {
+		if date != todayDate {
+			forecast.Velocity.CompletedCount += total.Count
+		}
+	}
+	perDay := float64(forecast.Velocity.CompletedCount) / float64(days)
+	forecast.Velocity.PerDay = math.Round(perDay*100) / 100
+
+	// 未完成的任务及其进度
+	var tasks []models.Task
+	if err := s.db.Where("user_id = ? AND completed = ?", userID, false).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	taskIDs := make([]uint, len(tasks))
+	for i := range tasks {
+		taskIDs[i] = tasks[i].ID
+	}
+	progress, err := models.PomodoroStatsByTask(s.db, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].SetPomodoroProgress(progress[tasks[i].ID].CompletedCount)
+	}
+
+	// 待办队列：有截止日期的在前，按截止日期、优先级、创建顺序排序
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.DueDate.IsZero() != b.DueDate.IsZero() {
+			return !a.DueDate.IsZero()
+		}
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.Before(b.DueDate)
+		}
+		if priorityRank[a.Priority] != priorityRank[b.Priority] {
+			return priorityRank[a.Priority] < priorityRank[b.Priority]
+		}
+		return a.ID < b.ID
+	})
+
+	// 今天按平均速度还能完成的数量
+	capacityToday := math.Max(perDay-float64(forecast.TodayCount), 0)
+
+	var queued int64
+	for i := range tasks {
+		task := &tasks[i]
+		result := &models.TaskForecast{Position: i + 1, Overdue: task.IsOverdue()}
+
+		var finish time.Time
+		if task.RemainingPomodoros == nil {
+			result.Reason = models.ForecastNotEstimated
+		} else {
+			queued += *task.RemainingPomodoros
+			switch rest := float64(queued) - capacityToday; {
+			case rest <= 0:
+				finish = today
+			case perDay == 0:
+				result.Reason = models.ForecastNoVelocity
+			default:
+				finish = today.AddDate(0, 0, int(math.Ceil(rest/perDay)))
+			}
+		}
+		if !finish.IsZero() {
+			date := finish.Format("2006-01-02")
+			result.Date = &date
+		}
+
+		result.AtRisk = task.IsAtRisk(finish, loc)
+		if result.AtRisk {
+			forecast.AtRiskCount++
+		}
+		task.Forecast = result
+	}
+
+	forecast.Tasks = tasks
+	return forecast, nil
+}
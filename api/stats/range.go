@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"TomatoList/models"
+)
+
+// 统计的时间粒度
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+	GranularityYear  = "year"
+)
+
+// MaxBuckets 单次统计最多返回的时间段数量，防止超大范围按小时统计
+const MaxBuckets = 2000
+
+// ErrTooManyBuckets 统计范围包含的时间段超过MaxBuckets
+var ErrTooManyBuckets = fmt.Errorf("时间范围过大，最多返回%d个时间段", MaxBuckets)
+
+// ValidGranularity 检查统计粒度是否有效
+func ValidGranularity(granularity string) bool {
+	switch granularity {
+	case GranularityHour, GranularityDay, GranularityWeek, GranularityMonth, GranularityYear:
+		return true
+	}
+	return false
+}
+
+// StartOfDay 返回t在loc时区中所在日期的零点
+// 使用日历日期计算，夏令时切换当天的长度可能不是24小时
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// CalendarDays 计算两个日期之间的日历天数，不受夏令时影响
+func CalendarDays(from, to time.Time) int {
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// Range 统计的时间范围[From, To)，按用户时区的日历日期对齐
+type Range struct {
+	From time.Time
+	To   time.Time
+	Days int // 范围包含的天数
+}
+
+// NewRange 创建从from到to（均为零点，to不包含）的时间范围
+func NewRange(from, to time.Time) Range {
+	return Range{From: from, To: to, Days: CalendarDays(from, to)}
+}
+
+// LastDays 返回截至今天（含）的最近days天
+func LastDays(now time.Time, loc *time.Location, days int) Range {
+	today := StartOfDay(now, loc)
+	return Range{From: today.AddDate(0, 0, -(days - 1)), To: today.AddDate(0, 0, 1), Days: days}
+}
+
+// Previous 返回紧邻在当前范围之前、天数相同的时间范围，用于环比
+func (r Range) Previous() Range {
+	return Range{From: r.From.AddDate(0, 0, -r.Days), To: r.From, Days: r.Days}
+}
+
+// FromDate 返回范围第一天的日期
+func (r Range) FromDate() string {
+	return r.From.Format("2006-01-02")
+}
+
+// ToDate 返回范围最后一天（包含）的日期
+func (r Range) ToDate() string {
+	return r.To.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// ParseWeekday 解析星期名称（如monday、sunday），不区分大小写
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// Bucketer 按粒度将时间划分到用户时区的时间段
+type Bucketer struct {
+	Granularity string
+	WeekStart   time.Weekday
+	Location    *time.Location
+}
+
+// Start 返回t所在时间段的开始时间
+func (b Bucketer) Start(t time.Time) time.Time {
+	t = t.In(b.Location)
+	switch b.Granularity {
+	case GranularityHour:
+		// 按绝对时间回退到本地整点，夏令时回拨时重复的那一小时会分成两个时间段
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case GranularityWeek:
+		offset := (int(t.Weekday()) - int(b.WeekStart) + 7) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, b.Location)
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, b.Location)
+	case GranularityYear:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, b.Location)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, b.Location)
+	}
+}
+
+// Next 返回下一个时间段的开始时间，start必须是时间段的开始时间
+func (b Bucketer) Next(start time.Time) time.Time {
+	switch b.Granularity {
+	case GranularityHour:
+		return start.Add(time.Hour)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	case GranularityYear:
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Label 返回时间段的显示标签
+func (b Bucketer) Label(start time.Time) string {
+	start = start.In(b.Location)
+	switch b.Granularity {
+	case GranularityHour:
+		return start.Format("2006-01-02T15:00")
+	case GranularityMonth:
+		return start.Format("2006-01")
+	case GranularityYear:
+		return start.Format("2006")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// Buckets 返回覆盖统计范围的所有时间段开始时间，用于补齐没有数据的时间段
+func (b Bucketer) Buckets(r Range) ([]time.Time, error) {
+	var starts []time.Time
+	for start := b.Start(r.From); start.Before(r.To); start = b.Next(start) {
+		if len(starts) == MaxBuckets {
+			return nil, ErrTooManyBuckets
+		}
+		starts = append(starts, start)
+	}
+	return starts, nil
+}
+
+// Minutes 将秒数换算为分钟，保留两位小数
+func Minutes(seconds int64) float64 {
+	return models.Minutes(seconds)
+}
+
+// PercentChange 计算相对上一周期的变化百分比，保留一位小数；上一周期为0时返回nil
+func PercentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/previous*1000) / 10
+	return &change
+}
+
+// Share 计算part占total的百分比，保留一位小数；total为0时返回0
+func Share(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
@@ -0,0 +1,159 @@
+package stats
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// rollupTable 每日汇总表名
+const rollupTable = "pomodoro_daily_rollups"
+
+// DayTotal 某一天已完成的工作时段数量和净专注秒数
+type DayTotal struct {
+	Count          int64
+	FocusedSeconds int64
+}
+
+// point 某个时间点已完成的工作时段：原始记录为开始时间，每日汇总为当天零点
+type point struct {
+	At             time.Time
+	Count          int64
+	FocusedSeconds int64
+}
+
+// resolve 决定统计数据来源
+// 每日汇总按用户资料中的时区分日，只有请求时区与之相同且粒度不小于一天时才能读取汇总，否则查询原始记录
+func (s *Service) resolve(q *Query) error {
+	q.rollups = false
+	if q.Bucketer.Granularity == GranularityHour {
+		return nil
+	}
+	loc, err := models.GetUserLocation(s.db, q.UserID)
+	if err != nil {
+		return err
+	}
+	q.rollups = loc.String() == q.Bucketer.Location.String()
+	return nil
+}
+
+// scope 返回时间范围内用户统计数据的查询，来源为每日汇总或原始番茄钟记录
+func (s *Service) scope(q Query, r Range) *gorm.DB {
+	var query *gorm.DB
+	if q.rollups {
+		query = s.db.Table(rollupTable).
+			Where(rollupTable+".user_id = ? AND "+rollupTable+".date >= ? AND "+rollupTable+".date < ?",
+				q.UserID, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+	} else {
+		query = s.db.Model(&models.Pomodoro{}).
+			Where("pomodoros.user_id = ? AND pomodoros.start_time >= ? AND pomodoros.start_time < ?", q.UserID, r.From.UTC(), r.To.UTC())
+	}
+	if !q.IncludeManual {
+		query = query.Where(q.column("is_manual")+" = ?", false)
+	}
+	return query
+}
+
+// column 返回带表名的列名
+func (q Query) column(name string) string {
+	if q.rollups {
+		return rollupTable + "." + name
+	}
+	return "pomodoros." + name
+}
+
+// count 返回番茄钟数量的聚合表达式，cond不为空时只统计满足条件的记录
+// 原始记录每行计1，每日汇总每行计其count列
+func (q Query) count(cond string) string {
+	weight := "1"
+	if q.rollups {
+		weight = q.column("count")
+	}
+	if cond == "" {
+		return "COALESCE(SUM(" + weight + "), 0)"
+	}
+	return "COALESCE(SUM(CASE WHEN " + cond + " THEN " + weight + " ELSE 0 END), 0)"
+}
+
+// focusedSeconds 返回净专注秒数的聚合表达式
+func (q Query) focusedSeconds() string {
+	return "COALESCE(SUM(" + q.column("focused_seconds") + "), 0)"
+}
+
+// points 查询时间范围内已完成的工作时段，按时间升序排列
+func (s *Service) points(q Query, r Range) ([]point, error) {
+	query := s.scope(q, r).
+		Where(q.column("kind")+" = ? AND "+q.column("status")+" = ?", models.KindWork, models.StatusCompleted)
+
+	if !q.rollups {
+		var sessions []struct {
+			StartTime      time.Time
+			FocusedSeconds int64
+		}
+		err := query.Select("pomodoros.start_time, pomodoros.focused_seconds").
+			Order("pomodoros.start_time").
+			Scan(&sessions).Error
+		if err != nil {
+			return nil, err
+		}
+
+		points := make([]point, len(sessions))
+		for i, session := range sessions {
+			points[i] = point{At: session.StartTime, Count: 1, FocusedSeconds: session.FocusedSeconds}
+		}
+		return points, nil
+	}
+
+	var days []struct {
+		Date           string
+		Count          int64
+		FocusedSeconds int64
+	}
+	err := query.Select(q.column("date") + " AS date, " + q.count("") + " AS count, " + q.focusedSeconds() + " AS focused_seconds").
+		Group(q.column("date")).
+		Order(q.column("date")).
+		Scan(&days).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]point, 0, len(days))
+	for _, day := range days {
+		at, err := time.ParseInLocation("2006-01-02", day.Date, q.Bucketer.Location)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point{At: at, Count: day.Count, FocusedSeconds: day.FocusedSeconds})
+	}
+	return points, nil
+}
+
+// DailyTotals 按用户时区的日期汇总时间范围内已完成的工作时段（包含补录）
+func (s *Service) DailyTotals(userID uint, loc *time.Location, r Range) (map[string]DayTotal, error) {
+	q := Query{
+		UserID:        userID,
+		Range:         r,
+		Bucketer:      Bucketer{Granularity: GranularityDay, Location: loc},
+		IncludeManual: true,
+	}
+	if err := s.resolve(&q); err != nil {
+		return nil, err
+	}
+
+	points, err := s.points(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]DayTotal)
+	for _, p := range points {
+		date := p.At.In(loc).Format("2006-01-02")
+		total := totals[date]
+		total.Count += p.Count
+		total.FocusedSeconds += p.FocusedSeconds
+		totals[date] = total
+	}
+	return totals, nil
+}
@@ -0,0 +1,418 @@
+// Package stats 番茄钟统计服务
+// 聚合查询只使用PostgreSQL和SQLite都支持的SQL（COUNT、SUM、CASE、COALESCE），
+// 时长统一以整数秒聚合后在Go中换算为分钟，按日期分组在Go中按用户时区完成。
+// 番茄钟时间以UTC保存，SQLite按字符串比较时间，查询范围绑定前同样转换为UTC。
+// 请求时区与用户资料一致且粒度不小于一天时读取每日汇总表，否则查询原始番茄钟记录
+package stats
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"TomatoList/models"
+)
+
+// 分组方式
+const (
+	GroupByTask    = "task"
+	GroupByProject = "project"
+)
+
+// Service 统计服务
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService 创建统计服务
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Query 统计查询条件
+type Query struct {
+	UserID        uint
+	Range         Range
+	Bucketer      Bucketer
+	IncludeManual bool   // 是否包含手动补录的番茄钟
+	GroupBy       string // 分组方式，为空时不分组
+
+	rollups bool // 是否读取每日汇总表，由resolve决定
+}
+
+// DailyStat 每日完成的工作时段数量
+type DailyStat struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// SeriesPoint 按粒度分组的一个时间段
+type SeriesPoint struct {
+	Start   time.Time `json:"start"`
+	Label   string    `json:"label"`
+	Count   int       `json:"count"`
+	Minutes float64   `json:"minutes"`
+}
+
+// SessionDistractions 单个番茄钟的干扰次数
+type SessionDistractions struct {
+	PomodoroID uint  `json:"pomodoroId"`
+	Count      int64 `json:"count"`
+}
+
+// DistractionStats 干扰统计
+type DistractionStats struct {
+	Total      int64                 `json:"total"`
+	ByType     map[string]int64      `json:"byType"`
+	PerSession float64               `json:"perSession"` // 平均每个工作时段（已完成或中断）的干扰次数
+	BySession  []SessionDistractions `json:"bySession"`
+}
+
+// BreakStats 休息时段统计
+type BreakStats struct {
+	ShortBreakCount int64   `json:"shortBreakCount"`
+	LongBreakCount  int64   `json:"longBreakCount"`
+	TotalMinutes    float64 `json:"totalMinutes"`
+}
+
+// Comparison 与上一个等长周期的对比
+type Comparison struct {
+	From           string   `json:"from"`
+	To             string   `json:"to"`
+	CompletedCount int64    `json:"completedCount"`
+	TotalMinutes   float64  `json:"totalMinutes"`
+	CountChange    *float64 `json:"countChange"`   // 数量变化百分比，上一周期为0时为null
+	MinutesChange  *float64 `json:"minutesChange"` // 时长变化百分比，上一周期为0时为null
+}
+
+// Breakdown 按任务分组统计的一项
+type Breakdown struct {
+	TaskID  *uint   `json:"taskId"`
+	Title   string  `json:"title"`
+	Count   int64   `json:"count"`
+	Minutes float64 `json:"minutes"`
+	Share   float64 `json:"share"` // 占总专注时长的百分比
+}
+
+// ProjectBreakdown 按项目分组统计的一项
+type ProjectBreakdown struct {
+	ProjectID *uint   `json:"projectId"`
+	Title     string  `json:"title"`
+	Color     string  `json:"color"`
+	Count     int64   `json:"count"`
+	Minutes   float64 `json:"minutes"`
+	Share     float64 `json:"share"` // 占总专注时长的百分比
+}
+
+// Summary 番茄钟统计结果
+type Summary struct {
+	CompletedCount   int64            `json:"completedCount"`
+	ManualCount      int64            `json:"manualCount"`
+	InterruptedCount int64            `json:"interruptedCount"`
+	Interruptions    map[string]int64 `json:"interruptions"`
+	TotalMinutes     float64          `json:"totalMinutes"` // 已完成工作时段的净专注时长（分钟）
+	DailyStats       []DailyStat      `json:"dailyStats"`
+	Distractions     DistractionStats `json:"distractions"`
+	Breaks           BreakStats       `json:"breaks"`
+	Series           []SeriesPoint    `json:"series"`
+	Comparison       Comparison       `json:"comparison"`
+	Breakdown        interface{}      `json:"breakdown"` // 按任务分组为[]Breakdown，按项目分组为[]ProjectBreakdown
+	From             string           `json:"from"`
+	To               string           `json:"to"`
+	Granularity      string           `json:"granularity"`
+	GroupBy          string           `json:"groupBy"`
+	Period           int              `json:"period"`
+	Timezone         string           `json:"timezone"`
+	IncludeManual    bool             `json:"includeManual"`
+}
+
+// workTotals 已完成工作时段的数量、补录数量和净专注秒数
+type workTotals struct {
+	CompletedCount int64
+	ManualCount    int64
+	FocusedSeconds int64
+}
+
+// workTotals 查询时间范围内已完成工作时段的汇总
+func (s *Service) workTotals(q Query, r Range) (workTotals, error) {
+	var totals workTotals
+	err := s.scope(q, r).
+		Select(q.count("")+" AS completed_count, "+
+			q.count(q.column("is_manual")+" = ?")+" AS manual_count, "+
+			q.focusedSeconds()+" AS focused_seconds", true).
+		Where(q.column("kind")+" = ? AND "+q.column("status")+" = ?", models.KindWork, models.StatusCompleted).
+		Scan(&totals).Error
+	return totals, err
+}
+
+// Summary 计算番茄钟统计
+func (s *Service) Summary(q Query) (*Summary, error) {
+	bucketStarts, err := q.Bucketer.Buckets(q.Range)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolve(&q); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		From:          q.Range.FromDate(),
+		To:            q.Range.ToDate(),
+		Granularity:   q.Bucketer.Granularity,
+		GroupBy:       q.GroupBy,
+		Period:        q.Range.Days,
+		Timezone:      q.Bucketer.Location.String(),
+		IncludeManual: q.IncludeManual,
+	}
+
+	// 已完成的工作时段
+	totals, err := s.workTotals(q, q.Range)
+	if err != nil {
+		return nil, err
+	}
+	summary.CompletedCount = totals.CompletedCount
+	summary.ManualCount = totals.ManualCount
+	summary.TotalMinutes = Minutes(totals.FocusedSeconds)
+
+	// 中断的工作时段（按原因分组）
+	if err := s.interruptions(q, summary); err != nil {
+		return nil, err
+	}
+
+	// 休息时段
+	if err := s.breaks(q, summary); err != nil {
+		return nil, err
+	}
+
+	// 干扰记录
+	if err := s.distractions(q, summary); err != nil {
+		return nil, err
+	}
+
+	// 每日数量和按粒度分组的时间序列
+	if err := s.series(q, bucketStarts, summary); err != nil {
+		return nil, err
+	}
+
+	// 与上一个等长周期对比
+	previousRange := q.Range.Previous()
+	previous, err := s.workTotals(q, previousRange)
+	if err != nil {
+		return nil, err
+	}
+	summary.Comparison = Comparison{
+		From:           previousRange.FromDate(),
+		To:             previousRange.ToDate(),
+		CompletedCount: previous.CompletedCount,
+		TotalMinutes:   Minutes(previous.FocusedSeconds),
+		CountChange:    PercentChange(float64(totals.CompletedCount), float64(previous.CompletedCount)),
+		MinutesChange:  PercentChange(float64(totals.FocusedSeconds), float64(previous.FocusedSeconds)),
+	}
+
+	// 分组统计
+	if q.GroupBy != "" {
+		breakdown, err := s.breakdown(q, totals.FocusedSeconds)
+		if err != nil {
+			return nil, err
+		}
+		summary.Breakdown = breakdown
+	}
+
+	return summary, nil
+}
+
+// interruptions 统计中断的工作时段数量（按原因分组）
+func (s *Service) interruptions(q Query, summary *Summary) error {
+	var groups []struct {
+		InterruptReason string
+		Count           int64
+	}
+	err := s.scope(q, q.Range).
+		Select(q.column("interrupt_reason")+" AS interrupt_reason, "+q.count("")+" AS count").
+		Where(q.column("kind")+" = ? AND "+q.column("status")+" = ?", models.KindWork, models.StatusInterrupted).
+		Group(q.column("interrupt_reason")).
+		Scan(&groups).Error
+	if err != nil {
+		return err
+	}
+
+	summary.Interruptions = make(map[string]int64, len(groups))
+	for _, group := range groups {
+		summary.Interruptions[group.InterruptReason] = group.Count
+		summary.InterruptedCount += group.Count
+	}
+	return nil
+}
+
+// breaks 统计已完成的休息时段数量和时长
+func (s *Service) breaks(q Query, summary *Summary) error {
+	var totals struct {
+		ShortBreakCount int64
+		LongBreakCount  int64
+		FocusedSeconds  int64
+	}
+	err := s.scope(q, q.Range).
+		Select(q.count(q.column("kind")+" = ?")+" AS short_break_count, "+
+			q.count(q.column("kind")+" = ?")+" AS long_break_count, "+
+			q.focusedSeconds()+" AS focused_seconds", models.KindShortBreak, models.KindLongBreak).
+		Where(q.column("kind")+" IN ? AND "+q.column("status")+" = ?", []string{models.KindShortBreak, models.KindLongBreak}, models.StatusCompleted).
+		Scan(&totals).Error
+	if err != nil {
+		return err
+	}
+
+	summary.Breaks = BreakStats{
+		ShortBreakCount: totals.ShortBreakCount,
+		LongBreakCount:  totals.LongBreakCount,
+		TotalMinutes:    Minutes(totals.FocusedSeconds),
+	}
+	return nil
+}
+
+// distractions 统计干扰记录数量（按类型和番茄钟分组）
+func (s *Service) distractions(q Query, summary *Summary) error {
+	var groups []struct {
+		PomodoroID uint
+		Type       string
+		Count      int64
+	}
+	query := s.db.Model(&models.Distraction{}).
+		Select("distractions.pomodoro_id, distractions.type, COUNT(*) AS count").
+		Joins("JOIN pomodoros ON pomodoros.id = distractions.pomodoro_id AND pomodoros.deleted_at IS NULL").
+		Where("pomodoros.user_id = ? AND pomodoros.start_time >= ? AND pomodoros.start_time < ?", q.UserID, q.Range.From.UTC(), q.Range.To.UTC())
+	if !q.IncludeManual {
+		query = query.Where("pomodoros.is_manual = ?", false)
+	}
+	err := query.Group("distractions.pomodoro_id, distractions.type").
+		Order("distractions.pomodoro_id").
+		Scan(&groups).Error
+	if err != nil {
+		return err
+	}
+
+	stats := DistractionStats{
+		ByType:    map[string]int64{models.DistractionInternal: 0, models.DistractionExternal: 0},
+		BySession: []SessionDistractions{},
+	}
+	for _, group := range groups {
+		stats.ByType[group.Type] += group.Count
+		stats.Total += group.Count
+		if n := len(stats.BySession); n > 0 && stats.BySession[n-1].PomodoroID == group.PomodoroID {
+			stats.BySession[n-1].Count += group.Count
+		} else {
+			stats.BySession = append(stats.BySession, SessionDistractions{PomodoroID: group.PomodoroID, Count: group.Count})
+		}
+	}
+
+	if sessions := summary.CompletedCount + summary.InterruptedCount; sessions > 0 {
+		stats.PerSession = math.Round(float64(stats.Total)/float64(sessions)*100) / 100
+	}
+
+	summary.Distractions = stats
+	return nil
+}
+
+// series 按用户时区的日期和统计粒度分组已完成的工作时段，没有数据的时间段补0
+func (s *Service) series(q Query, bucketStarts []time.Time, summary *Summary) error {
+	points, err := s.points(q, q.Range)
+	if err != nil {
+		return err
+	}
+
+	loc := q.Bucketer.Location
+	summary.DailyStats = []DailyStat{}
+	for _, p := range points {
+		date := p.At.In(loc).Format("2006-01-02")
+		if n := len(summary.DailyStats); n > 0 && summary.DailyStats[n-1].Date == date {
+			summary.DailyStats[n-1].Count += int(p.Count)
+		} else {
+			summary.DailyStats = append(summary.DailyStats, DailyStat{Date: date, Count: int(p.Count)})
+		}
+	}
+
+	seconds := make([]int64, len(bucketStarts))
+	summary.Series = make([]SeriesPoint, len(bucketStarts))
+	index := make(map[int64]int, len(bucketStarts))
+	for i, start := range bucketStarts {
+		summary.Series[i] = SeriesPoint{Start: start, Label: q.Bucketer.Label(start)}
+		index[start.Unix()] = i
+	}
+	for _, p := range points {
+		if i, ok := index[q.Bucketer.Start(p.At).Unix()]; ok {
+			summary.Series[i].Count += int(p.Count)
+			seconds[i] += p.FocusedSeconds
+		}
+	}
+	for i := range summary.Series {
+		summary.Series[i].Minutes = Minutes(seconds[i])
+	}
+	return nil
+}
+
+// breakdownGroup 分组统计的一组
+type breakdownGroup struct {
+	ID             *uint
+	Title          string
+	Color          string
+	Count          int64
+	FocusedSeconds int64
+}
+
+// breakdown 按任务或项目分组统计已完成工作时段的数量、时长和占比
+// 未关联任务或项目的时段归为一组，其ID为null
+func (s *Service) breakdown(q Query, totalSeconds int64) (interface{}, error) {
+	var groups []breakdownGroup
+	query := s.scope(q, q.Range).
+		Joins("LEFT JOIN tasks ON tasks.id = "+q.column("task_id")).
+		Where(q.column("kind")+" = ? AND "+q.column("status")+" = ?", models.KindWork, models.StatusCompleted)
+	totals := q.count("") + " AS count, " + q.focusedSeconds() + " AS focused_seconds"
+	if q.GroupBy == GroupByProject {
+		query = query.
+			Select("tasks.project_id AS id, COALESCE(projects.name, '') AS title, COALESCE(projects.color, '') AS color, " + totals).
+			Joins("LEFT JOIN projects ON projects.id = tasks.project_id").
+			Group("tasks.project_id, projects.name, projects.color")
+	} else {
+		query = query.
+			Select(q.column("task_id") + " AS id, COALESCE(tasks.title, '') AS title, " + totals).
+			Group(q.column("task_id") + ", tasks.title")
+	}
+	err := query.Order("focused_seconds DESC").Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		// 每日汇总中未关联任务记为0
+		if groups[i].ID != nil && *groups[i].ID == 0 {
+			groups[i].ID = nil
+		}
+	}
+
+	if q.GroupBy == GroupByProject {
+		breakdown := make([]ProjectBreakdown, 0, len(groups))
+		for _, group := range groups {
+			breakdown = append(breakdown, ProjectBreakdown{
+				ProjectID: group.ID,
+				Title:     group.Title,
+				Color:     group.Color,
+				Count:     group.Count,
+				Minutes:   Minutes(group.FocusedSeconds),
+				Share:     Share(group.FocusedSeconds, totalSeconds),
+			})
+		}
+		return breakdown, nil
+	}
+
+	breakdown := make([]Breakdown, 0, len(groups))
+	for _, group := range groups {
+		breakdown = append(breakdown, Breakdown{
+			TaskID:  group.ID,
+			Title:   group.Title,
+			Count:   group.Count,
+			Minutes: Minutes(group.FocusedSeconds),
+			Share:   Share(group.FocusedSeconds, totalSeconds),
+		})
+	}
+	return breakdown, nil
+}
@@ -0,0 +1,389 @@
+package stats
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"TomatoList/models"
+)
+
+// testUserID 测试用户的ID
+const testUserID = 1
+
+// testEnv 使用内存SQLite的统计服务，用户资料时区为Asia/Shanghai
+type testEnv struct {
+	t   *testing.T
+	db  *gorm.DB
+	svc *Service
+	loc *time.Location // 用户资料时区，读取每日汇总
+	raw *time.Location // 与用户资料偏移相同但名称不同的时区，查询原始记录
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，只使用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{}, &models.Pomodoro{},
+		&models.PomodoroPause{}, &models.Distraction{}, &models.DailyRollup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Email: "stats@example.com", Password: "x", Name: "stats", Timezone: "Asia/Shanghai"}
+	user.ID = testUserID
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return &testEnv{
+		t:   t,
+		db:  db,
+		svc: NewService(db),
+		loc: mustLoadLocation(t, "Asia/Shanghai"),
+		raw: mustLoadLocation(t, "Asia/Chongqing"),
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// date 返回用户时区中的指定时刻
+func (e *testEnv) date(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, e.loc)
+}
+
+// add 以接口相同的方式保存已结束的番茄钟（时间为UTC），并更新每日汇总
+func (e *testEnv) add(p models.Pomodoro) models.Pomodoro {
+	e.t.Helper()
+	p.UserID = testUserID
+	if p.Kind == "" {
+		p.Kind = models.KindWork
+	}
+	if p.Status == "" {
+		p.Status = models.StatusCompleted
+	}
+	p.StartTime = p.StartTime.UTC()
+	p.EndTime = p.StartTime.Add(time.Duration(p.FocusedSeconds) * time.Second)
+	p.ExpectedEndTime = p.EndTime
+	err := e.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&p).Error; err != nil {
+			return err
+		}
+		return models.RefreshDailyRollups(tx, testUserID, p.StartTime)
+	})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return p
+}
+
+// summary 按天统计[from, to)，loc决定读取每日汇总还是原始记录
+func (e *testEnv) summary(loc *time.Location, from, to time.Time, includeManual bool) *Summary {
+	e.t.Helper()
+	summary, err := e.svc.Summary(e.query(loc, from, to, includeManual))
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return summary
+}
+
+func (e *testEnv) query(loc *time.Location, from, to time.Time, includeManual bool) Query {
+	return Query{
+		UserID:        testUserID,
+		Range:         NewRange(from.In(loc), to.In(loc)),
+		Bucketer:      Bucketer{Granularity: GranularityDay, Location: loc},
+		IncludeManual: includeManual,
+		GroupBy:       GroupByTask,
+	}
+}
+
+func TestResolveChoosesSource(t *testing.T) {
+	e := newTestEnv(t)
+	from := e.date(time.March, 1, 0, 0)
+
+	tests := []struct {
+		name        string
+		loc         *time.Location
+		granularity string
+		rollups     bool
+	}{
+		{"profile timezone", e.loc, GranularityDay, true},
+		{"other timezone", e.raw, GranularityDay, false},
+		{"hourly", e.loc, GranularityHour, false},
+	}
+	for _, tt := range tests {
+		q := e.query(tt.loc, from, from.AddDate(0, 0, 1), true)
+		q.Bucketer.Granularity = tt.granularity
+		if err := e.svc.resolve(&q); err != nil {
+			t.Fatal(err)
+		}
+		if q.rollups != tt.rollups {
+			t.Errorf("%s: rollups = %v, want %v", tt.name, q.rollups, tt.rollups)
+		}
+	}
+}
+
+func TestSummaryFractionalMinutes(t *testing.T) {
+	e := newTestEnv(t)
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 2, 9, 0), FocusedSeconds: 1490})
+
+	from := e.date(time.March, 2, 0, 0)
+	for _, loc := range []*time.Location{e.loc, e.raw} {
+		summary := e.summary(loc, from, from.AddDate(0, 0, 1), true)
+		if summary.TotalMinutes != 24.83 {
+			t.Errorf("%s: totalMinutes = %v, want 24.83", loc, summary.TotalMinutes)
+		}
+		if len(summary.Series) != 1 || summary.Series[0].Minutes != 24.83 {
+			t.Errorf("%s: series = %+v, want one bucket with 24.83 minutes", loc, summary.Series)
+		}
+		if breakdown, ok := summary.Breakdown.([]Breakdown); !ok || len(breakdown) != 1 || breakdown[0].Minutes != 24.83 || breakdown[0].Share != 100 {
+			t.Errorf("%s: breakdown = %+v, want one group with 24.83 minutes", loc, summary.Breakdown)
+		}
+	}
+}
+
+func TestBreakdownJSON(t *testing.T) {
+	e := newTestEnv(t)
+	project := models.Project{UserID: testUserID, Name: "工作", Color: "#336699"}
+	if err := e.db.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{Title: "写报告", UserID: testUserID, ProjectID: &project.ID}
+	if err := e.db.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	e.add(models.Pomodoro{TaskID: &task.ID, StartTime: e.date(time.March, 2, 9, 0), FocusedSeconds: 1800})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 2, 10, 0), FocusedSeconds: 600})
+
+	from := e.date(time.March, 2, 0, 0)
+	tests := []struct {
+		groupBy string
+		want    string
+	}{
+		{GroupByTask, `[{"taskId":1,"title":"写报告","count":1,"minutes":30,"share":75},` +
+			`{"taskId":null,"title":"","count":1,"minutes":10,"share":25}]`},
+		{GroupByProject, `[{"projectId":1,"title":"工作","color":"#336699","count":1,"minutes":30,"share":75},` +
+			`{"projectId":null,"title":"","color":"","count":1,"minutes":10,"share":25}]`},
+	}
+	for _, tt := range tests {
+		for _, loc := range []*time.Location{e.loc, e.raw} {
+			q := e.query(loc, from, from.AddDate(0, 0, 1), true)
+			q.GroupBy = tt.groupBy
+			summary, err := e.svc.Summary(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(summary.Breakdown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s, %s: breakdown = %s, want %s", tt.groupBy, loc, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSummaryQueryError(t *testing.T) {
+	tests := []struct {
+		name  string
+		table interface{}
+		raw   bool
+	}{
+		{"rollups", &models.DailyRollup{}, false},
+		{"raw", &models.Pomodoro{}, true},
+	}
+	for _, tt := range tests {
+		e := newTestEnv(t)
+		e.add(models.Pomodoro{StartTime: e.date(time.March, 2, 9, 0), FocusedSeconds: 1500})
+		if err := e.db.Migrator().DropTable(tt.table); err != nil {
+			t.Fatal(err)
+		}
+
+		loc := e.loc
+		if tt.raw {
+			loc = e.raw
+		}
+		from := e.date(time.March, 2, 0, 0)
+		summary, err := e.svc.Summary(e.query(loc, from, from.AddDate(0, 0, 1), true))
+		if err == nil {
+			t.Errorf("%s: Summary() = %+v, want error", tt.name, summary)
+		}
+		totals, err := e.svc.DailyTotals(testUserID, loc, NewRange(from, from.AddDate(0, 0, 1)))
+		if err == nil {
+			t.Errorf("%s: DailyTotals() = %+v, want error", tt.name, totals)
+		}
+	}
+}
+
+func TestSummaryFillsEmptyBuckets(t *testing.T) {
+	e := newTestEnv(t)
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 3, 10, 0), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 6, 22, 0), FocusedSeconds: 900})
+
+	from := e.date(time.March, 2, 0, 0)
+	want := []SeriesPoint{
+		{Label: "2026-03-02"},
+		{Label: "2026-03-03", Count: 1, Minutes: 25},
+		{Label: "2026-03-04"},
+		{Label: "2026-03-05"},
+		{Label: "2026-03-06", Count: 1, Minutes: 15},
+		{Label: "2026-03-07"},
+	}
+	for _, loc := range []*time.Location{e.loc, e.raw} {
+		summary := e.summary(loc, from, from.AddDate(0, 0, 6), true)
+		got := make([]SeriesPoint, len(summary.Series))
+		for i, point := range summary.Series {
+			if !point.Start.Equal(from.AddDate(0, 0, i)) {
+				t.Errorf("%s: series[%d].start = %v, want %v", loc, i, point.Start, from.AddDate(0, 0, i))
+			}
+			point.Start = time.Time{}
+			got[i] = point
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: series = %+v, want %+v", loc, got, want)
+		}
+	}
+}
+
+func TestSummaryComparison(t *testing.T) {
+	e := newTestEnv(t)
+	// 上一周期：3月1日至3月3日
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 1, 0, 10), FocusedSeconds: 1200})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 3, 23, 50), FocusedSeconds: 1200})
+	// 当前周期：3月4日至3月6日
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 4, 0, 5), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 5, 9, 0), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 6, 23, 30), FocusedSeconds: 1500})
+	// 两个周期之外
+	e.add(models.Pomodoro{StartTime: e.date(time.February, 28, 23, 59), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 7, 0, 0), FocusedSeconds: 1500})
+
+	countChange, minutesChange := 50.0, 87.5
+	want := Comparison{
+		From:           "2026-03-01",
+		To:             "2026-03-03",
+		CompletedCount: 2,
+		TotalMinutes:   40,
+		CountChange:    &countChange,
+		MinutesChange:  &minutesChange,
+	}
+	from := e.date(time.March, 4, 0, 0)
+	for _, loc := range []*time.Location{e.loc, e.raw} {
+		summary := e.summary(loc, from, from.AddDate(0, 0, 3), true)
+		if summary.CompletedCount != 3 || summary.TotalMinutes != 75 {
+			t.Errorf("%s: current = %d sessions, %v minutes, want 3 sessions, 75 minutes", loc, summary.CompletedCount, summary.TotalMinutes)
+		}
+		if !reflect.DeepEqual(summary.Comparison, want) {
+			t.Errorf("%s: comparison = %+v, want %+v", loc, summary.Comparison, want)
+		}
+	}
+
+	// 上一周期为0时变化为null
+	summary := e.summary(e.loc, e.date(time.February, 1, 0, 0), e.date(time.February, 2, 0, 0), true)
+	if summary.Comparison.CountChange != nil || summary.Comparison.MinutesChange != nil {
+		t.Errorf("comparison = %+v, want null changes", summary.Comparison)
+	}
+}
+
+func TestRollupsMatchRawRecords(t *testing.T) {
+	e := newTestEnv(t)
+	task := models.Task{Title: "写报告", UserID: testUserID}
+	if err := e.db.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 覆盖本地零点前后（UTC日期与本地日期不同）、补录、中断、休息和干扰记录
+	e.add(models.Pomodoro{TaskID: &task.ID, StartTime: e.date(time.March, 2, 0, 30), FocusedSeconds: 1490})
+	e.add(models.Pomodoro{TaskID: &task.ID, StartTime: e.date(time.March, 2, 7, 59), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 2, 8, 0), FocusedSeconds: 1500, IsManual: true})
+	e.add(models.Pomodoro{TaskID: &task.ID, StartTime: e.date(time.March, 3, 23, 45), FocusedSeconds: 600,
+		Status: models.StatusInterrupted, InterruptReason: models.InterruptMeeting})
+	e.add(models.Pomodoro{Kind: models.KindShortBreak, StartTime: e.date(time.March, 4, 12, 0), FocusedSeconds: 300})
+	e.add(models.Pomodoro{Kind: models.KindLongBreak, StartTime: e.date(time.March, 4, 13, 0), FocusedSeconds: 900})
+	distracted := e.add(models.Pomodoro{TaskID: &task.ID, StartTime: e.date(time.March, 5, 23, 59), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.February, 27, 23, 30), FocusedSeconds: 1500})
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 6, 0, 0), FocusedSeconds: 1500})
+	distraction := models.Distraction{PomodoroID: distracted.ID, UserID: testUserID, Type: models.DistractionInternal,
+		OccurredAt: distracted.StartTime.Add(time.Minute)}
+	if err := e.db.Create(&distraction).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := e.date(time.March, 1, 0, 0), e.date(time.March, 6, 0, 0)
+	for _, includeManual := range []bool{true, false} {
+		rollups := e.summary(e.loc, from, to, includeManual)
+		raw := e.summary(e.raw, from, to, includeManual)
+
+		if rollups.CompletedCount == 0 || rollups.InterruptedCount != 1 || rollups.Distractions.Total != 1 {
+			t.Fatalf("includeManual=%v: summary = %+v, want sessions in range", includeManual, rollups)
+		}
+		// 时间序列的开始时间使用各自的时区，比较绝对时间后忽略
+		if len(rollups.Series) != len(raw.Series) {
+			t.Fatalf("includeManual=%v: series length %d != %d", includeManual, len(rollups.Series), len(raw.Series))
+		}
+		for i := range rollups.Series {
+			if !rollups.Series[i].Start.Equal(raw.Series[i].Start) {
+				t.Errorf("includeManual=%v: series[%d].start %v != %v", includeManual, i, rollups.Series[i].Start, raw.Series[i].Start)
+			}
+			rollups.Series[i].Start, raw.Series[i].Start = time.Time{}, time.Time{}
+		}
+		raw.Timezone = rollups.Timezone
+		if !reflect.DeepEqual(rollups, raw) {
+			t.Errorf("includeManual=%v: rollups and raw records differ\nrollups: %+v\nraw:     %+v", includeManual, rollups, raw)
+		}
+	}
+
+	rollupTotals, err := e.svc.DailyTotals(testUserID, e.loc, NewRange(from, to))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawTotals, err := e.svc.DailyTotals(testUserID, e.raw, NewRange(from.In(e.raw), to.In(e.raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]DayTotal{
+		"2026-03-02": {Count: 3, FocusedSeconds: 4490},
+		"2026-03-05": {Count: 1, FocusedSeconds: 1500},
+	}
+	if !reflect.DeepEqual(rollupTotals, want) || !reflect.DeepEqual(rawTotals, want) {
+		t.Errorf("DailyTotals: rollups = %+v, raw = %+v, want %+v", rollupTotals, rawTotals, want)
+	}
+}
+
+func TestDailyTotalsRawPathUsesUTCBounds(t *testing.T) {
+	e := newTestEnv(t)
+	// 本地时间3月2日00:30，UTC为3月1日16:30
+	e.add(models.Pomodoro{StartTime: e.date(time.March, 2, 0, 30), FocusedSeconds: 1500})
+
+	from := e.date(time.March, 2, 0, 0)
+	for _, loc := range []*time.Location{e.loc, e.raw} {
+		totals, err := e.svc.DailyTotals(testUserID, loc, NewRange(from.In(loc), from.AddDate(0, 0, 1).In(loc)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := totals["2026-03-02"].Count; got != 1 {
+			t.Errorf("%s: count = %d, want 1", loc, got)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package workers
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"TomatoList/events"
+	"TomatoList/models"
+)
+
+// Sweeper 后台清理任务，处理超过预期结束时间仍未结束的番茄钟，以及暂停过久的番茄钟
+// 例如浏览器标签页被关闭时，番茄钟会一直停留在进行中或已暂停状态
+type Sweeper struct {
+	db          *gorm.DB
+	hub         *events.Hub
+	interval    time.Duration // 扫描间隔
+	gracePeriod time.Duration // 超过预期结束时间多久后才处理
+	maxPause    time.Duration // 暂停超过多久后视为已放弃
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSweeper 创建清理任务，处理结果通过hub推送给用户的客户端
+func NewSweeper(db *gorm.DB, hub *events.Hub, interval, gracePeriod, maxPause time.Duration) *Sweeper {
+	return &Sweeper{
+		db:          db,
+		hub:         hub,
+		interval:    interval,
+		gracePeriod: gracePeriod,
+		maxPause:    maxPause,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start 在后台协程中定期执行清理
+func (s *Sweeper) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				if err := s.Sweep(now); err != nil {
+					log.Println("Pomodoro sweep failed:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止清理任务，并等待正在执行的清理结束
+func (s *Sweeper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+// Sweep 执行一次清理：根据用户的超时处理策略将超时的番茄钟标记为已过期或自动完成，
+// 暂停超过maxPause的番茄钟以暂停开始时间作为结束时间标记为已过期
+func (s *Sweeper) Sweep(now time.Time) error {
+	var pomodoros []models.Pomodoro
+	err := s.db.Preload("Pauses").
+		Where("status = ? AND expected_end_time < ?", models.StatusRunning, now.Add(-s.gracePeriod).UTC()).
+		Or("status = ? AND EXISTS (SELECT 1 FROM pomodoro_pauses WHERE pomodoro_pauses.pomodoro_id = pomodoros.id "+
+			"AND pomodoro_pauses.end_time IS NULL AND pomodoro_pauses.deleted_at IS NULL AND pomodoro_pauses.start_time < ?)",
+			models.StatusPaused, now.Add(-s.maxPause).UTC()).
+		Find(&pomodoros).Error
+	if err != nil {
+		return err
+	}
+
+	policies := make(map[uint]string)
+	for i := range pomodoros {
+		pomodoro := &pomodoros[i]
+
+		// 暂停过久的番茄钟并未专注到预期结束时间，不按策略自动完成，以暂停开始时间作为结束时间
+		status, eventType, end := models.StatusExpired, events.PomodoroExpired, pomodoro.ExpectedEndTime
+		if pause := pomodoro.OpenPause(); pause != nil {
+			end = pause.StartTime
+		} else {
+			policy, ok := policies[pomodoro.UserID]
+			if !ok {
+				settings, err := models.GetTimerSettings(s.db, pomodoro.UserID)
+				if err != nil {
+					return err
+				}
+				policy = settings.OverduePolicy
+				policies[pomodoro.UserID] = policy
+			}
+			if policy == models.OverduePolicyComplete {
+				status, eventType = models.StatusCompleted, events.PomodoroCompleted
+			}
+		}
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			return pomodoro.Finish(tx, status, end, map[string]interface{}{})
+		})
+
+		// 用户在扫描期间已经手动结束了番茄钟，跳过即可
+		var transitionErr *models.TransitionError
+		if errors.As(err, &transitionErr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		s.hub.Publish(pomodoro.UserID, eventType, *pomodoro)
+	}
+
+	return nil
+}